@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandAbility(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of an ability", nil
+	}
+	abilityName := commandArgs[0]
+
+	ability, err := cliState.APIService.GetAbilityContext(ctx, abilityName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "ability", abilityName, err)
+	}
+
+	output := fmt.Sprintf("Name: %s\n", ability.Name)
+	output += fmt.Sprintf("Introduced in: %s\n", ability.Generation.Name)
+	if effect := ability.Effect("en"); effect != "" {
+		output += fmt.Sprintf("Effect: %s\n", strings.Join(strings.Fields(effect), " "))
+	} else if effect := ability.ShortEffect("en"); effect != "" {
+		output += fmt.Sprintf("Effect: %s\n", effect)
+	}
+
+	output += "Pokemon:\n"
+	for _, abilityPokemon := range ability.Pokemon {
+		output += "  - " + abilityPokemon.Pokemon.Name
+		if abilityPokemon.IsHidden {
+			output += " (hidden)"
+		}
+		output += "\n"
+	}
+	return output, nil
+}
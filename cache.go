@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weirdwyrd/pokego/internal"
+	"github.com/weirdwyrd/pokego/internal/pokecache"
+)
+
+const (
+	// listCacheTTL keeps lists short, they grow when PokeAPI adds data
+	listCacheTTL = 5 * time.Second
+	// resourceCacheTTL keeps PokeAPI resources in memory for long, they practically never change
+	resourceCacheTTL = time.Hour
+	// staleCacheFor is how long an expired entry is still served while it is refetched in the background
+	staleCacheFor = 10 * time.Minute
+	// diskListCacheTTL keeps lists on disk for a while, resources stay for the whole disk TTL
+	diskListCacheTTL = time.Hour
+	// notFoundCacheTTL remembers names PokeAPI does not know, so a typo repeated right away costs no request
+	notFoundCacheTTL = 30 * time.Second
+)
+
+// newCache builds the memory cache, in front of a disk cache when diskDir is set.
+// refresh fetches the data of a key again, to revalidate stale entries.
+func newCache(refresh func(ctx context.Context, key string) ([]byte, error), diskDir string, diskTTL time.Duration) (internal.LoadingCache, error) {
+	memoryCache, err := pokecache.NewCache(resourceCacheTTL,
+		pokecache.WithMaxEntries(1000),
+		pokecache.WithMaxBytes(64<<20),
+		pokecache.WithNamespaceTTL(internal.ListCacheNamespace, listCacheTTL),
+		pokecache.WithStaleWhileRevalidate(staleCacheFor, refresh),
+		pokecache.WithNegativeCaching(notFoundCacheTTL, func(err error) bool {
+			return errors.Is(err, internal.ErrNotFound)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if diskDir == "" {
+		return memoryCache, nil
+	}
+
+	diskCache, err := pokecache.NewDiskCache(diskDir,
+		pokecache.WithDiskTTL(diskTTL),
+		pokecache.WithDiskNamespaceTTL(internal.ListCacheNamespace, diskListCacheTTL),
+	)
+	if err != nil {
+		// the disk only saves requests, so carry on without it
+		fmt.Println("Error opening the disk cache, caching in memory only:", err)
+		return memoryCache, nil
+	}
+	return pokecache.NewTieredCache(memoryCache, diskCache), nil
+}
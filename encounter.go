@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+var fishingRods = []string{"old-rod", "good-rod", "super-rod"}
+
+func commandWalk(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	_, flags := parseFlags(commandArgs)
+	return lookForPokemon(ctx, cliState, "walk", flags["version"])
+}
+
+func commandSurf(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	_, flags := parseFlags(commandArgs)
+	return lookForPokemon(ctx, cliState, "surf", flags["version"])
+}
+
+func commandFish(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	positional, flags := parseFlags(commandArgs)
+	if len(positional) == 0 || !slices.Contains(fishingRods, positional[0]) {
+		return "Which rod? fish <old-rod|good-rod|super-rod>", nil
+	}
+	return lookForPokemon(ctx, cliState, positional[0], flags["version"])
+}
+
+func commandFlee(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if cliState.WildEncounter == nil {
+		return "There is nothing to run from", nil
+	}
+	pokemonName := cliState.WildEncounter.Name
+	cliState.WildEncounter = nil
+	return fmt.Sprintf("Got away safely from the wild %s!", pokemonName), nil
+}
+
+// lookForPokemon rolls a random encounter with the given method in the current area
+func lookForPokemon(ctx context.Context, cliState *internal.CliState, method, version string) (string, error) {
+	if wild := cliState.WildEncounter; wild != nil {
+		return fmt.Sprintf("A wild %s is still in front of you! catch it or flee", wild.Name), nil
+	}
+	if cliState.CurrentArea == "" {
+		return "You are nowhere yet, goto an area first", nil
+	}
+
+	locationArea, err := cliState.APIService.GetLocationAreaContext(ctx, cliState.CurrentArea)
+	if err != nil {
+		return "", err
+	}
+	if rate, _ := locationArea.EncounterRate(method, version); rate == 0 {
+		return fmt.Sprintf("You can't %s in %s", methodVerb(method), locationArea.Name), nil
+	}
+
+	wild, found := locationArea.RollEncounter(method, version, cliState.Rand.IntN)
+	if !found {
+		return "Nothing appeared...", nil
+	}
+	cliState.WildEncounter = &wild
+	return fmt.Sprintf("A wild %s (level %d) appeared!", wild.Name, wild.Level), nil
+}
+
+func methodVerb(method string) string {
+	if slices.Contains(fishingRods, method) {
+		return "fish with the " + method
+	}
+	return method
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandEvolution(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of a Pokemon", nil
+	}
+	pokemonName := commandArgs[0]
+
+	// Pokedex entries know their species, anything else is looked up by name
+	name := pokemonName
+	if entry, exists := cliState.Pokedex[pokemonName]; exists {
+		name = speciesName(entry.Pokemon)
+	}
+
+	species, err := cliState.APIService.GetPokemonSpeciesContext(ctx, name)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "pokemon-species", name, err)
+	}
+	chain, err := getEvolutionChain(ctx, cliState, species)
+	if err != nil {
+		return "", err
+	}
+
+	return renderEvolutionTree(chain.Chain), nil
+}
+
+// renderEvolutionTree draws a chain as an ASCII tree with the conditions of every evolution
+func renderEvolutionTree(root internal.ChainLink) string {
+	var sb strings.Builder
+	sb.WriteString(root.Species.Name + "\n")
+	writeEvolutions(&sb, root, "")
+	return sb.String()
+}
+
+func writeEvolutions(sb *strings.Builder, link internal.ChainLink, prefix string) {
+	for i, next := range link.EvolvesTo {
+		branch, indent := "|-- ", "|   "
+		if i == len(link.EvolvesTo)-1 {
+			branch, indent = "`-- ", "    "
+		}
+
+		conditions := make([]string, len(next.EvolutionDetails))
+		for j, detail := range next.EvolutionDetails {
+			conditions[j] = detail.String()
+		}
+
+		sb.WriteString(prefix + branch + next.Species.Name)
+		if len(conditions) > 0 {
+			sb.WriteString(" (" + strings.Join(conditions, " or ") + ")")
+		}
+		sb.WriteString("\n")
+		writeEvolutions(sb, next, prefix+indent)
+	}
+}
+
+func commandEvolve(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of a Pokemon in your Pokedex", nil
+	}
+	pokemonName := commandArgs[0]
+	entry, exists := cliState.Pokedex[pokemonName]
+	if !exists {
+		return fmt.Sprintf("You don't have %s in your Pokedex", pokemonName), nil
+	}
+
+	species, err := cliState.APIService.GetPokemonSpeciesContext(ctx, speciesName(entry.Pokemon))
+	if err != nil {
+		return "", err
+	}
+	chain, err := getEvolutionChain(ctx, cliState, species)
+	if err != nil {
+		return "", err
+	}
+	link, ok := chain.Chain.Find(species.Name)
+	if !ok || len(link.EvolvesTo) == 0 {
+		return fmt.Sprintf("%s does not evolve any further", pokemonName), nil
+	}
+
+	state := internal.EvolutionState{
+		Level: entry.Level,
+		// nothing raises happiness yet, so it stays what the species starts with
+		Happiness: species.BaseHappiness,
+		TimeOfDay: internal.TimeOfDay(time.Now().Hour()),
+	}
+	if len(commandArgs) > 1 {
+		state.Item = commandArgs[1]
+	}
+
+	output := ""
+	for _, next := range link.EvolvesTo {
+		for _, detail := range next.EvolutionDetails {
+			unmet := detail.Unmet(state)
+			if len(unmet) > 0 {
+				output += fmt.Sprintf("%s can't evolve into %s: %s\n", pokemonName, next.Species.Name, strings.Join(unmet, ", "))
+				continue
+			}
+
+			evolved, err := cliState.APIService.GetPokemonContext(ctx, next.Species.Name)
+			if err != nil {
+				return "", err
+			}
+			delete(cliState.Pokedex, pokemonName)
+			cliState.Pokedex[evolved.Name] = internal.PokedexEntry{
+				Pokemon: evolved,
+				Level:   entry.Level,
+			}
+			return fmt.Sprintf("What? %s is evolving!\nCongratulations! Your %s evolved into %s!", pokemonName, pokemonName, evolved.Name), nil
+		}
+	}
+	return output, nil
+}
+
+func getEvolutionChain(ctx context.Context, cliState *internal.CliState, species internal.PokemonSpecies) (internal.EvolutionChain, error) {
+	chainID, err := species.EvolutionChain.ID()
+	if err != nil {
+		return internal.EvolutionChain{}, fmt.Errorf("%s has no evolution chain: %w", species.Name, err)
+	}
+
+	return cliState.APIService.GetEvolutionChainContext(ctx, chainID)
+}
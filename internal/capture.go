@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"math"
+	"slices"
+)
+
+// Balls lists the Poke Balls the capture formula knows, by PokeAPI item name
+var Balls = []string{
+	"poke-ball", "great-ball", "ultra-ball", "master-ball",
+	"net-ball", "dusk-ball", "quick-ball", "nest-ball", "repeat-ball", "timer-ball",
+}
+
+// StartingInventory is the bag of a new game
+func StartingInventory() map[string]int {
+	inventory := map[string]int{
+		"poke-ball":   50,
+		"great-ball":  10,
+		"ultra-ball":  5,
+		"master-ball": 1,
+	}
+	for _, ball := range Balls {
+		if _, ok := inventory[ball]; !ok {
+			inventory[ball] = 3
+		}
+	}
+	return inventory
+}
+
+// IsBall reports whether name is one of Balls
+func IsBall(name string) bool {
+	return slices.Contains(Balls, name)
+}
+
+// StatusConditions lists the status conditions that make a Pokemon easier to catch
+var StatusConditions = []string{"sleep", "freeze", "paralysis", "poison", "burn"}
+
+// CaptureAttempt is everything the generation III+ capture formula looks at when a ball is thrown
+type CaptureAttempt struct {
+	CaptureRate int      // capture_rate of the species, from 3 (legendaries) to 255
+	HP          float64  // current HP over max HP, 0 counts as full HP
+	Status      string   // one of StatusConditions or empty
+	Ball        string   // one of Balls, empty for a plain poke-ball
+	Types       []string // of the wild Pokemon, for the net-ball
+	Level       int      // of the wild Pokemon, for the nest-ball
+	Turn        int      // throws so far including this one, for the quick-ball and timer-ball
+	Night       bool     // for the dusk-ball
+	Caught      bool     // the species is already in the Pokedex, for the repeat-ball
+}
+
+// BallModifier is how much the ball multiplies the catch rate, following generation V rules
+func (c CaptureAttempt) BallModifier() float64 {
+	switch c.Ball {
+	case "great-ball":
+		return 1.5
+	case "ultra-ball":
+		return 2
+	case "master-ball":
+		return 255
+	case "net-ball":
+		if slices.Contains(c.Types, "water") || slices.Contains(c.Types, "bug") {
+			return 3
+		}
+	case "dusk-ball":
+		if c.Night {
+			return 3.5
+		}
+	case "quick-ball":
+		if c.Turn <= 1 {
+			return 5
+		}
+	case "nest-ball":
+		return max(1, float64(41-c.Level)/10)
+	case "repeat-ball":
+		if c.Caught {
+			return 3
+		}
+	case "timer-ball":
+		return min(4, 1+0.3*float64(max(0, c.Turn-1)))
+	}
+	return 1
+}
+
+// StatusModifier is how much the status condition multiplies the catch rate
+func (c CaptureAttempt) StatusModifier() float64 {
+	switch c.Status {
+	case "sleep", "freeze":
+		return 2.5
+	case "paralysis", "poison", "burn":
+		return 1.5
+	}
+	return 1
+}
+
+// CatchValue is the modified catch rate a, 255 or more is a guaranteed catch
+func (c CaptureAttempt) CatchValue() int {
+	hp := c.HP
+	if hp <= 0 || hp > 1 {
+		hp = 1
+	}
+	// (3 * maxHP - 2 * HP) / (3 * maxHP) with HP as a fraction of maxHP
+	a := (3 - 2*hp) / 3 * float64(c.CaptureRate) * c.BallModifier() * c.StatusModifier()
+	return max(1, int(a))
+}
+
+// ShakeProbability is b, every shake check passes when a random number below 65536 is less than b
+func (c CaptureAttempt) ShakeProbability() int {
+	a := c.CatchValue()
+	if a >= 255 {
+		return 65536
+	}
+	return int(1048560 / math.Sqrt(math.Sqrt(16711680/float64(a))))
+}
+
+// Chance is the probability that the Pokemon is caught, which takes four successful shake checks
+func (c CaptureAttempt) Chance() float64 {
+	return math.Pow(min(1, float64(c.ShakeProbability())/65536), 4)
+}
+
+// Throw runs the four shake checks and returns how many passed, all four means the Pokemon is caught.
+// intn must return a number in [0, n), which lets callers choose the random source.
+func (c CaptureAttempt) Throw(intn func(n int) int) int {
+	b := c.ShakeProbability()
+	if b >= 65536 {
+		return 4
+	}
+	for check := range 4 {
+		if intn(65536) >= b {
+			return check
+		}
+	}
+	return 4
+}
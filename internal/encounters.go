@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// EncounterSummary is how a Pokemon can be met in an area with one method under the same conditions
+type EncounterSummary struct {
+	Pokemon    string
+	Version    string
+	Method     string // walk, surf, old-rod...
+	MinLevel   int
+	MaxLevel   int
+	Chance     int // summed over the encounter slots, in percent
+	Conditions []string
+}
+
+// Encounters summarizes the encounters of the area for one game version, or for every version when version is empty.
+// Slots that only differ by level are merged, summing their chances.
+func (a LocationArea) Encounters(version string) []EncounterSummary {
+	var summaries []EncounterSummary
+	index := make(map[string]int)
+
+	for _, pokemonEncounter := range a.PokemonEncounters {
+		for _, versionDetail := range pokemonEncounter.VersionDetails {
+			if version != "" && versionDetail.Version.Name != version {
+				continue
+			}
+			for _, encounter := range versionDetail.EncounterDetails {
+				conditions := make([]string, len(encounter.ConditionValues))
+				for i, condition := range encounter.ConditionValues {
+					conditions[i] = condition.Name
+				}
+				slices.Sort(conditions)
+
+				key := strings.Join([]string{pokemonEncounter.Pokemon.Name, versionDetail.Version.Name, encounter.Method.Name, strings.Join(conditions, ",")}, "|")
+				if i, seen := index[key]; seen {
+					summaries[i].MinLevel = min(summaries[i].MinLevel, encounter.MinLevel)
+					summaries[i].MaxLevel = max(summaries[i].MaxLevel, encounter.MaxLevel)
+					summaries[i].Chance += encounter.Chance
+					continue
+				}
+				index[key] = len(summaries)
+				summaries = append(summaries, EncounterSummary{
+					Pokemon:    pokemonEncounter.Pokemon.Name,
+					Version:    versionDetail.Version.Name,
+					Method:     encounter.Method.Name,
+					MinLevel:   encounter.MinLevel,
+					MaxLevel:   encounter.MaxLevel,
+					Chance:     encounter.Chance,
+					Conditions: conditions,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(summaries, func(a, b EncounterSummary) int {
+		return cmp.Or(
+			cmp.Compare(a.Version, b.Version),
+			cmp.Compare(a.Method, b.Method),
+			cmp.Compare(b.Chance, a.Chance),
+			cmp.Compare(a.Pokemon, b.Pokemon),
+		)
+	})
+	return summaries
+}
+
+// Versions lists the game versions with encounters in the area
+func (a LocationArea) Versions() []string {
+	var versions []string
+	for _, pokemonEncounter := range a.PokemonEncounters {
+		for _, versionDetail := range pokemonEncounter.VersionDetails {
+			if !slices.Contains(versions, versionDetail.Version.Name) {
+				versions = append(versions, versionDetail.Version.Name)
+			}
+		}
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// WildPokemon is a Pokemon met in the wild, it stays around until it is caught or the player flees
+type WildPokemon struct {
+	Name    string `json:"name"`
+	Level   int    `json:"level"`
+	Method  string `json:"method"`
+	Area    string `json:"area"`
+	Version string `json:"version"`
+	Throws  int    `json:"throws"` // balls thrown at it so far
+}
+
+// EncounterRate is the chance in percent that a step using method meets a wild Pokemon.
+// Without a version the first version that supports the method is used, which is also returned.
+func (a LocationArea) EncounterRate(method, version string) (int, string) {
+	for _, methodRate := range a.EncounterMethodRates {
+		if methodRate.EncounterMethod.Name != method {
+			continue
+		}
+		for _, versionDetail := range methodRate.VersionDetails {
+			if version == "" || versionDetail.Version.Name == version {
+				return versionDetail.Rate, versionDetail.Version.Name
+			}
+		}
+	}
+	return 0, version
+}
+
+// RollEncounter takes one step with method: it rolls against the encounter rate, then picks an
+// encounter slot weighted by its chance and a level within the slot's range.
+// Slots that depend on conditions such as time of day or swarms are not modelled and never picked.
+// intn must return a number in [0, n), which lets callers choose the random source.
+func (a LocationArea) RollEncounter(method, version string, intn func(n int) int) (WildPokemon, bool) {
+	rate, version := a.EncounterRate(method, version)
+	if rate == 0 || intn(100) >= rate {
+		return WildPokemon{}, false
+	}
+
+	type slot struct {
+		pokemon string
+		Encounter
+	}
+	var slots []slot
+	total := 0
+	for _, pokemonEncounter := range a.PokemonEncounters {
+		for _, versionDetail := range pokemonEncounter.VersionDetails {
+			if versionDetail.Version.Name != version {
+				continue
+			}
+			for _, encounter := range versionDetail.EncounterDetails {
+				if encounter.Method.Name != method || len(encounter.ConditionValues) > 0 || encounter.Chance <= 0 {
+					continue
+				}
+				slots = append(slots, slot{pokemon: pokemonEncounter.Pokemon.Name, Encounter: encounter})
+				total += encounter.Chance
+			}
+		}
+	}
+	if total == 0 {
+		return WildPokemon{}, false
+	}
+
+	roll := intn(total)
+	for _, s := range slots {
+		if roll >= s.Chance {
+			roll -= s.Chance
+			continue
+		}
+		return WildPokemon{
+			Name:    s.pokemon,
+			Level:   s.MinLevel + intn(max(0, s.MaxLevel-s.MinLevel)+1),
+			Method:  method,
+			Area:    a.Name,
+			Version: version,
+		}, true
+	}
+	return WildPokemon{}, false
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotFound matches any API error caused by a resource that does not exist
+var ErrNotFound = errors.New("not found")
+
+// HTTPStatusError is returned when PokeAPI answers with a status other than 200 OK
+type HTTPStatusError struct {
+	StatusCode int
+	URL        string
+}
+
+// newHTTPStatusError takes the URL that was requested, res.Request is only set by some transports
+func newHTTPStatusError(res *http.Response, url string) *HTTPStatusError {
+	return &HTTPStatusError{
+		StatusCode: res.StatusCode,
+		URL:        url,
+	}
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.StatusCode == http.StatusNotFound {
+		return fmt.Sprintf("%s: %s", ErrNotFound, e.URL)
+	}
+	return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
+}
+
+// Is lets errors.Is(err, ErrNotFound) match a 404
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// DecodeError is returned when a response body is not the JSON we expect
+type DecodeError struct {
+	Resource string
+	URL      string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s from %s: %v", e.Resource, e.URL, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
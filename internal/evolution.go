@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Find returns the link of the chain that holds the given species
+func (c ChainLink) Find(species string) (ChainLink, bool) {
+	if c.Species.Name == species {
+		return c, true
+	}
+	for _, next := range c.EvolvesTo {
+		if link, ok := next.Find(species); ok {
+			return link, true
+		}
+	}
+	return ChainLink{}, false
+}
+
+// EvolutionState is what the game knows about a Pokemon when checking whether it can evolve
+type EvolutionState struct {
+	Level     int
+	Happiness int
+	Item      string // item the player uses on the Pokemon, if any
+	TimeOfDay string // "day", "dusk" or "night"
+}
+
+// String describes the conditions, e.g. "level 16" or "use water-stone"
+func (d EvolutionDetail) String() string {
+	var conditions []string
+	switch d.Trigger.Name {
+	case "level-up":
+		if d.MinLevel != nil {
+			conditions = append(conditions, fmt.Sprintf("level %d", *d.MinLevel))
+		} else {
+			conditions = append(conditions, "level up")
+		}
+	case "use-item":
+		if d.Item != nil {
+			conditions = append(conditions, "use "+d.Item.Name)
+		}
+	case "trade":
+		conditions = append(conditions, "trade")
+	default:
+		conditions = append(conditions, d.Trigger.Name)
+	}
+
+	if d.HeldItem != nil {
+		conditions = append(conditions, "holding "+d.HeldItem.Name)
+	}
+	if d.MinHappiness != nil {
+		conditions = append(conditions, fmt.Sprintf("happiness %d", *d.MinHappiness))
+	}
+	if d.MinBeauty != nil {
+		conditions = append(conditions, fmt.Sprintf("beauty %d", *d.MinBeauty))
+	}
+	if d.MinAffection != nil {
+		conditions = append(conditions, fmt.Sprintf("affection %d", *d.MinAffection))
+	}
+	if d.KnownMove != nil {
+		conditions = append(conditions, "knowing "+d.KnownMove.Name)
+	}
+	if d.KnownMoveType != nil {
+		conditions = append(conditions, "knowing a "+d.KnownMoveType.Name+" move")
+	}
+	if d.Location != nil {
+		conditions = append(conditions, "at "+d.Location.Name)
+	}
+	if d.TimeOfDay != "" {
+		conditions = append(conditions, "during the "+d.TimeOfDay)
+	}
+	if d.Gender != nil {
+		conditions = append(conditions, map[int]string{1: "female", 2: "male"}[*d.Gender])
+	}
+	if d.NeedsOverworldRain {
+		conditions = append(conditions, "in the rain")
+	}
+	if d.PartySpecies != nil {
+		conditions = append(conditions, "with "+d.PartySpecies.Name+" in the party")
+	}
+	if d.PartyType != nil {
+		conditions = append(conditions, "with a "+d.PartyType.Name+" Pokemon in the party")
+	}
+	if d.TradeSpecies != nil {
+		conditions = append(conditions, "for "+d.TradeSpecies.Name)
+	}
+	if d.RelativePhysicalStats != nil {
+		conditions = append(conditions, map[int]string{1: "attack > defense", 0: "attack = defense", -1: "attack < defense"}[*d.RelativePhysicalStats])
+	}
+	if d.TurnUpsideDown {
+		conditions = append(conditions, "holding the console upside down")
+	}
+	return strings.Join(conditions, ", ")
+}
+
+// Unmet lists the conditions the state does not satisfy; an empty list means the Pokemon can evolve.
+// Conditions the game does not model, like trading or party members, are always unmet.
+func (d EvolutionDetail) Unmet(state EvolutionState) []string {
+	var unmet []string
+	switch d.Trigger.Name {
+	case "level-up":
+		// no level requirement means the next level up is enough
+	case "use-item":
+		if d.Item == nil || d.Item.Name != state.Item {
+			unmet = append(unmet, d.String())
+		}
+	default:
+		unmet = append(unmet, d.Trigger.Name+" is not supported")
+	}
+
+	if d.MinLevel != nil && state.Level < *d.MinLevel {
+		unmet = append(unmet, fmt.Sprintf("needs level %d (is %d)", *d.MinLevel, state.Level))
+	}
+	if d.MinHappiness != nil && state.Happiness < *d.MinHappiness {
+		unmet = append(unmet, fmt.Sprintf("needs happiness %d (is %d)", *d.MinHappiness, state.Happiness))
+	}
+	if d.TimeOfDay != "" && d.TimeOfDay != state.TimeOfDay {
+		unmet = append(unmet, "needs to be "+d.TimeOfDay+" time (is "+state.TimeOfDay+")")
+	}
+
+	unsupported := []struct {
+		condition string
+		required  bool
+	}{
+		{"held item", d.HeldItem != nil},
+		{"known move", d.KnownMove != nil || d.KnownMoveType != nil},
+		{"location", d.Location != nil},
+		{"gender", d.Gender != nil},
+		{"beauty", d.MinBeauty != nil},
+		{"affection", d.MinAffection != nil},
+		{"rain", d.NeedsOverworldRain},
+		{"party", d.PartySpecies != nil || d.PartyType != nil},
+		{"stats", d.RelativePhysicalStats != nil},
+		{"upside down", d.TurnUpsideDown},
+		{"trade partner", d.TradeSpecies != nil},
+	}
+	for _, u := range unsupported {
+		if u.required {
+			unmet = append(unmet, u.condition+" conditions are not supported")
+		}
+	}
+	return unmet
+}
+
+// TimeOfDay maps an hour of the day to the names PokeAPI uses in evolution conditions
+func TimeOfDay(hour int) string {
+	switch {
+	case hour >= 4 && hour < 17:
+		return "day"
+	case hour == 17:
+		return "dusk"
+	default:
+		return "night"
+	}
+}
+
+// ID is the numeric id at the end of a resource URL such as .../evolution-chain/67/
+func (r APIResource) ID() (int, error) {
+	segments := strings.Split(strings.TrimRight(r.URL, "/"), "/")
+	id, err := strconv.Atoi(segments[len(segments)-1])
+	if err != nil {
+		return 0, fmt.Errorf("no id in resource url %q", r.URL)
+	}
+	return id, nil
+}
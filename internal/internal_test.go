@@ -1,9 +1,30 @@
 package internal
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+// newTestService starts an httptest.Server with the given handler and returns a service pointed at it
+func newTestService(t *testing.T, handler http.HandlerFunc, opts ...PokeAPIOption) *PokeAPIService {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewPokeAPIService(append([]PokeAPIOption{WithBaseURL(server.URL)}, opts...)...)
+}
+
 // TestGetLocationAreas tests the PokeAPI service's ability to fetch location areas
 func TestGetLocationAreas(t *testing.T) {
 	service := NewPokeAPIService()
@@ -33,3 +54,985 @@ func TestGetLocationAreas(t *testing.T) {
 		t.Error("Expected different location areas in different pages")
 	}
 }
+
+// TestServiceOptions tests that the functional options reach the outgoing requests
+func TestServiceOptions(t *testing.T) {
+	var gotPath, gotUserAgent string
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUserAgent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"id": 25, "name": "pikachu", "base_experience": 112}`))
+	}, WithUserAgent("pokego-test"), WithTimeout(time.Second))
+
+	pokemon, err := service.GetPokemon("pikachu")
+	if err != nil {
+		t.Fatalf("Failed to get pokemon: %v", err)
+	}
+
+	if pokemon.Name != "pikachu" || pokemon.BaseExperience != 112 {
+		t.Errorf("Unexpected pokemon decoded: %+v", pokemon)
+	}
+	if gotPath != "/pokemon/pikachu" {
+		t.Errorf("Expected path /pokemon/pikachu, got %s", gotPath)
+	}
+	if gotUserAgent != "pokego-test" {
+		t.Errorf("Expected User-Agent pokego-test, got %s", gotUserAgent)
+	}
+}
+
+// TestWithHTTPClientIsCopied tests that options never mutate a caller-owned client
+func TestWithHTTPClientIsCopied(t *testing.T) {
+	client := &http.Client{}
+	NewPokeAPIService(WithHTTPClient(client), WithTimeout(time.Second))
+
+	if client.Timeout != 0 {
+		t.Errorf("Expected caller client to be untouched, got timeout %v", client.Timeout)
+	}
+}
+
+// TestContextCancelsRequest tests that cancelling the context aborts a slow request
+func TestContextCancelsRequest(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := service.GetLocationAreaContext(ctx, "canalave-city-area")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// testRetryPolicy retries quickly so tests don't wait on real backoff
+func testRetryPolicy(maxAttempts int) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = maxAttempts
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	return policy
+}
+
+// TestRetryTransientStatus tests that retryable statuses are retried until the request succeeds
+func TestRetryTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	var retries []RetryEvent
+	policy := testRetryPolicy(3)
+	policy.OnRetry = func(event RetryEvent) {
+		retries = append(retries, event)
+	}
+
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": 1, "name": "bulbasaur"}`))
+	}, WithRetryPolicy(policy))
+
+	pokemon, err := service.GetPokemon("bulbasaur")
+	if err != nil {
+		t.Fatalf("Expected retries to succeed, got %v", err)
+	}
+	if pokemon.Name != "bulbasaur" {
+		t.Errorf("Expected bulbasaur, got %s", pokemon.Name)
+	}
+	if len(retries) != 2 || retries[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 2 observed retries on 503, got %+v", retries)
+	}
+}
+
+// TestRetryExhausted tests that giving up reports the number of attempts
+func TestRetryExhausted(t *testing.T) {
+	var calls atomic.Int32
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, WithRetryPolicy(testRetryPolicy(4)))
+
+	_, err := service.GetPokemon("mew")
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Expected a *RetryError, got %v", err)
+	}
+	if retryErr.Attempts != 4 || calls.Load() != 4 {
+		t.Errorf("Expected 4 attempts, error says %d and server saw %d", retryErr.Attempts, calls.Load())
+	}
+}
+
+// TestNoRetryOnClientError tests that non-retryable statuses are returned immediately
+func TestNoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}, WithRetryPolicy(testRetryPolicy(4)))
+
+	if _, err := service.GetPokemon("missingno"); err == nil {
+		t.Fatal("Expected an error for a 404")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single attempt, server saw %d", calls.Load())
+	}
+}
+
+// TestParseRetryAfter tests both forms of the Retry-After header
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{header: "", expected: 0},
+		{header: "3", expected: 3 * time.Second},
+		{header: now.Add(2 * time.Second).Format(http.TimeFormat), expected: 2 * time.Second},
+		{header: "soon", expected: 0},
+	}
+
+	for _, test := range tests {
+		if actual := parseRetryAfter(test.header, now); actual != test.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", test.header, actual, test.expected)
+		}
+	}
+}
+
+// TestBackoff tests the bounds of the jittered backoff, including a zero base delay and overflowing shifts
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		baseDelay  time.Duration
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{baseDelay: 0, attempt: 1, min: 0, max: 0},
+		{baseDelay: 0, attempt: 70, min: 0, max: 0},
+		{baseDelay: 0, attempt: 3, retryAfter: 2 * time.Second, min: 2 * time.Second, max: 2 * time.Second},
+		{baseDelay: 100 * time.Millisecond, attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{baseDelay: 100 * time.Millisecond, attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{baseDelay: 100 * time.Millisecond, attempt: 40, min: 5 * time.Second, max: 10 * time.Second},
+		{baseDelay: 100 * time.Millisecond, attempt: 70, min: 5 * time.Second, max: 10 * time.Second},
+		{baseDelay: 100 * time.Millisecond, attempt: 1, retryAfter: time.Minute, min: 10 * time.Second, max: 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		policy := RetryPolicy{BaseDelay: test.baseDelay, MaxDelay: 10 * time.Second}
+		for range 20 {
+			if delay := policy.backoff(test.attempt, test.retryAfter); delay < test.min || delay > test.max {
+				t.Errorf("backoff(%d, %v) with base %v = %v, want between %v and %v", test.attempt, test.retryAfter, test.baseDelay, delay, test.min, test.max)
+				break
+			}
+		}
+	}
+}
+
+// TestTypedErrors tests that API failures can be told apart with errors.Is and errors.As
+func TestTypedErrors(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pokemon/missingno":
+			w.WriteHeader(http.StatusNotFound)
+		case "/pokemon/glitch":
+			w.Write([]byte(`{"id": "not a number"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}, WithRetryPolicy(testRetryPolicy(1)))
+
+	_, err := service.GetPokemon("missingno")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	_, err = service.GetPokemon("glitch")
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a *DecodeError, got %v", err)
+	}
+
+	_, err = service.GetPokemon("forbidden")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected a 403 *HTTPStatusError, got %v", err)
+	}
+	if statusErr.URL == "" || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a URL and no ErrNotFound match, got %+v", statusErr)
+	}
+}
+
+// roundTripFunc is a custom transport, unlike http.Transport it leaves Response.Request unset
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestCustomTransportErrors tests that status errors work with a transport that does not set Response.Request
+func TestCustomTransportErrors(t *testing.T) {
+	service := NewPokeAPIService(
+		WithBaseURL("https://pokeapi.test/api/v2"),
+		WithRetryPolicy(testRetryPolicy(1)),
+		WithTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			status := http.StatusNotFound
+			if req.URL.Path == "/api/v2/pokemon/glitch" {
+				status = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody}, nil
+		})),
+	)
+
+	_, err := service.GetPokemon("missingno")
+	var statusErr *HTTPStatusError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &statusErr) || statusErr.URL != "https://pokeapi.test/api/v2/pokemon/missingno" {
+		t.Errorf("Expected a 404 for the requested URL, got %v", err)
+	}
+
+	_, err = service.GetPokemon("glitch")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.URL != "https://pokeapi.test/api/v2/pokemon/glitch" {
+		t.Errorf("Expected a 503 for the requested URL, got %v", err)
+	}
+}
+
+// TestRateLimiter tests that the bucket allows a burst and then spaces requests out
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(10, 2)
+	limiter.now = func() time.Time { return now }
+
+	if delay := limiter.reserve(); delay != 0 {
+		t.Errorf("Expected first request of the burst to pass, waited %v", delay)
+	}
+	if delay := limiter.reserve(); delay != 0 {
+		t.Errorf("Expected second request of the burst to pass, waited %v", delay)
+	}
+	if delay := limiter.reserve(); delay != 100*time.Millisecond {
+		t.Errorf("Expected third request to wait 100ms, waited %v", delay)
+	}
+
+	// a second later the bucket has refilled to its burst size
+	now = now.Add(time.Second)
+	if delay := limiter.reserve(); delay != 0 {
+		t.Errorf("Expected refilled bucket to pass, waited %v", delay)
+	}
+}
+
+// TestRateLimitedService tests that the service reports time spent waiting on a shared limiter
+func TestRateLimitedService(t *testing.T) {
+	var waits atomic.Int32
+	limiter := NewRateLimiter(20, 1)
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1, "name": "bulbasaur"}`))
+	}, WithRateLimiter(limiter), WithThrottleHook(func(url string, waited time.Duration) {
+		waits.Add(1)
+	}))
+
+	for range 3 {
+		if _, err := service.GetPokemon("bulbasaur"); err != nil {
+			t.Fatalf("Failed to get pokemon: %v", err)
+		}
+	}
+	if waits.Load() != 2 {
+		t.Errorf("Expected 2 throttled requests after a burst of 1, got %d", waits.Load())
+	}
+}
+
+// newPagedService serves count location areas, honoring limit and offset like PokeAPI does
+func newPagedService(t *testing.T, count int, requests *atomic.Int32) *PokeAPIService {
+	t.Helper()
+	return newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		page := Page[NamedAPIResource]{Count: count}
+		for i := offset; i < min(count, offset+limit); i++ {
+			page.Results = append(page.Results, NamedAPIResource{Name: fmt.Sprintf("area-%d", i)})
+		}
+		if offset+limit < count {
+			page.Next = fmt.Sprintf("http://%s/location-area?limit=%d&offset=%d", r.Host, limit, offset+limit)
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+}
+
+// TestListLocationAreas tests limit, offset and the page numbers derived from them
+func TestListLocationAreas(t *testing.T) {
+	var requests atomic.Int32
+	service := newPagedService(t, 45, &requests)
+
+	page, err := service.ListLocationAreasContext(context.Background(), 20, 40)
+	if err != nil {
+		t.Fatalf("Failed to list location areas: %v", err)
+	}
+
+	if len(page.Results) != 5 || page.Results[0].Name != "area-40" {
+		t.Errorf("Expected areas 40-44, got %+v", page.Results)
+	}
+	if page.PageNumber() != 3 || page.TotalPages() != 3 || page.HasNext() {
+		t.Errorf("Expected last page 3 of 3, got page %d of %d (next %q)", page.PageNumber(), page.TotalPages(), page.Next)
+	}
+}
+
+// TestAllLocationAreas tests that the iterator follows next links lazily
+func TestAllLocationAreas(t *testing.T) {
+	var requests atomic.Int32
+	service := newPagedService(t, 25, &requests)
+
+	var names []string
+	for area, err := range service.AllLocationAreas(context.Background(), 10) {
+		if err != nil {
+			t.Fatalf("Failed to walk location areas: %v", err)
+		}
+		names = append(names, area.Name)
+	}
+	if len(names) != 25 || names[24] != "area-24" || requests.Load() != 3 {
+		t.Errorf("Expected 25 areas over 3 requests, got %d over %d", len(names), requests.Load())
+	}
+
+	// stopping early must not fetch the remaining pages
+	requests.Store(0)
+	for range service.AllLocationAreas(context.Background(), 10) {
+		break
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected a single request when stopping early, got %d", requests.Load())
+	}
+}
+
+// mapCache is a minimal LoadingCache for tests
+type mapCache map[string][]byte
+
+func (c mapCache) Get(key string) ([]byte, bool) {
+	val, ok := c[key]
+	return val, ok
+}
+
+func (c mapCache) Add(key string, val []byte) {
+	c[key] = val
+}
+
+func (c mapCache) GetOrLoad(key string, loader func() ([]byte, error)) ([]byte, error) {
+	if val, ok := c[key]; ok {
+		return val, nil
+	}
+	val, err := loader()
+	if err == nil {
+		c[key] = val
+	}
+	return val, err
+}
+
+// TestGenericGet tests URL resolution, caching and metrics of the generic fetcher
+func TestGenericGet(t *testing.T) {
+	var paths []string
+	cache := mapCache{}
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"id": 132, "name": "ditto", "count": 1, "results": [{"name": "ditto"}]}`))
+	}, WithCache(cache))
+
+	// resource URLs from the public API are served by the configured base URL
+	pokemon, err := Get[Pokemon](context.Background(), service, "https://pokeapi.co/api/v2/pokemon/132/")
+	if err != nil {
+		t.Fatalf("Failed to get pokemon: %v", err)
+	}
+	if pokemon.Name != "ditto" || len(paths) != 1 || paths[0] != "/pokemon/132/" {
+		t.Errorf("Expected ditto from /pokemon/132/, got %s from %v", pokemon.Name, paths)
+	}
+
+	if _, err := Get[Pokemon](context.Background(), service, "pokemon/132/"); err != nil {
+		t.Fatalf("Failed to get cached pokemon: %v", err)
+	}
+	metrics := service.Metrics()
+	if len(paths) != 1 || metrics.CacheHits != 1 || metrics.Requests != 1 {
+		t.Errorf("Expected the second call to hit the cache, got %d requests and %+v", len(paths), metrics)
+	}
+
+	// lists are cached in their own namespace, and every key can be fetched again from its name alone
+	if _, err := List[NamedAPIResource](context.Background(), service, "pokemon", 1, 0); err != nil {
+		t.Fatalf("Failed to list pokemon: %v", err)
+	}
+	for _, key := range []string{"pokemon/132/", "list:pokemon?limit=1&offset=0"} {
+		if _, ok := cache[key]; !ok {
+			t.Errorf("Expected %s to be cached, got %v", key, slices.Collect(maps.Keys(cache)))
+		}
+	}
+	if _, err := service.FetchCacheKey(context.Background(), "list:pokemon?limit=1&offset=0"); err != nil || paths[len(paths)-1] != "/pokemon" {
+		t.Errorf("Expected the list key to fetch /pokemon again, got %v, %v", paths, err)
+	}
+}
+
+// TestPokemonSpecies tests decoding a species and picking its English Pokedex text
+func TestPokemonSpecies(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"id": 1, "name": "bulbasaur", "capture_rate": 45, "base_happiness": 50,
+			"is_legendary": false, "growth_rate": {"name": "medium-slow"},
+			"egg_groups": [{"name": "monster"}, {"name": "plant"}],
+			"habitat": {"name": "grassland"}, "generation": {"name": "generation-i"},
+			"evolution_chain": {"url": "https://pokeapi.co/api/v2/evolution-chain/1/"},
+			"flavor_text_entries": [
+				{"flavor_text": "A strange seed was\nplanted on its\fback at birth.", "language": {"name": "en"}, "version": {"name": "red"}},
+				{"flavor_text": "Au matin de sa vie, la graine sur son dos lui fournit les éléments dont il a besoin pour grandir.", "language": {"name": "fr"}, "version": {"name": "x"}}
+			],
+			"genera": [{"genus": "Pokémon Graine", "language": {"name": "fr"}}, {"genus": "Seed Pokémon", "language": {"name": "en"}}]
+		}`))
+	})
+
+	species, err := service.GetPokemonSpeciesContext(context.Background(), "bulbasaur")
+	if err != nil {
+		t.Fatalf("Failed to get species: %v", err)
+	}
+
+	if species.CaptureRate != 45 || species.GrowthRate.Name != "medium-slow" || species.Habitat.Name != "grassland" {
+		t.Errorf("Unexpected species decoded: %+v", species)
+	}
+	if text := species.FlavorText("en"); text != "A strange seed was planted on its back at birth." {
+		t.Errorf("Unexpected flavor text %q", text)
+	}
+	if genus := species.Genus("en"); genus != "Seed Pokémon" {
+		t.Errorf("Unexpected genus %q", genus)
+	}
+}
+
+// TestEvolutionConditions tests finding a species in a chain and checking its evolution conditions
+func TestEvolutionConditions(t *testing.T) {
+	var chain EvolutionChain
+	err := json.Unmarshal([]byte(`{"id": 67, "chain": {
+		"species": {"name": "eevee"},
+		"evolves_to": [
+			{"species": {"name": "vaporeon"}, "evolution_details": [{"trigger": {"name": "use-item"}, "item": {"name": "water-stone"}}]},
+			{"species": {"name": "umbreon"}, "evolution_details": [{"trigger": {"name": "level-up"}, "min_happiness": 160, "time_of_day": "night"}]}
+		]
+	}}`), &chain)
+	if err != nil {
+		t.Fatalf("Failed to decode chain: %v", err)
+	}
+
+	link, ok := chain.Chain.Find("eevee")
+	if !ok || len(link.EvolvesTo) != 2 {
+		t.Fatalf("Expected eevee with 2 evolutions, got %+v", link)
+	}
+	if _, ok := chain.Chain.Find("pikachu"); ok {
+		t.Error("Expected pikachu not to be part of the eevee chain")
+	}
+
+	waterStone := link.EvolvesTo[0].EvolutionDetails[0]
+	if unmet := waterStone.Unmet(EvolutionState{Item: "water-stone"}); len(unmet) != 0 {
+		t.Errorf("Expected the water stone to evolve eevee, got %v", unmet)
+	}
+	if unmet := waterStone.Unmet(EvolutionState{Item: "fire-stone"}); len(unmet) != 1 {
+		t.Errorf("Expected the fire stone to be rejected, got %v", unmet)
+	}
+
+	friendship := link.EvolvesTo[1].EvolutionDetails[0]
+	if unmet := friendship.Unmet(EvolutionState{Happiness: 70, TimeOfDay: "day"}); len(unmet) != 2 {
+		t.Errorf("Expected happiness and time of day to be unmet, got %v", unmet)
+	}
+	if unmet := friendship.Unmet(EvolutionState{Happiness: 220, TimeOfDay: "night"}); len(unmet) != 0 {
+		t.Errorf("Expected umbreon conditions to be met, got %v", unmet)
+	}
+	if description := friendship.String(); description != "level up, happiness 160, during the night" {
+		t.Errorf("Unexpected description %q", description)
+	}
+}
+
+// TestTypeChart tests single and dual type effectiveness
+func TestTypeChart(t *testing.T) {
+	relation := func(names ...string) []NamedAPIResource {
+		resources := make([]NamedAPIResource, len(names))
+		for i, name := range names {
+			resources[i] = NamedAPIResource{Name: name}
+		}
+		return resources
+	}
+	chart, err := NewTypeChart([]Type{
+		{Name: "electric", DamageRelations: TypeRelations{
+			NoDamageTo:     relation("ground"),
+			HalfDamageTo:   relation("electric", "grass", "dragon"),
+			DoubleDamageTo: relation("flying", "water"),
+		}},
+		{Name: "ice", DamageRelations: TypeRelations{
+			HalfDamageTo:   relation("fire", "water", "ice", "steel"),
+			DoubleDamageTo: relation("grass", "ground", "flying", "dragon"),
+		}},
+		{Name: "shadow"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build type chart: %v", err)
+	}
+
+	tests := []struct {
+		attacking string
+		defending []string
+		expected  float64
+	}{
+		{attacking: "electric", defending: []string{"water"}, expected: 2},
+		{attacking: "electric", defending: []string{"water", "flying"}, expected: 4},
+		{attacking: "electric", defending: []string{"ground", "flying"}, expected: 0},
+		{attacking: "electric", defending: []string{"grass", "dragon"}, expected: 0.25},
+		{attacking: "ice", defending: []string{"dragon", "flying"}, expected: 4},
+		{attacking: "normal", defending: []string{"ghost"}, expected: 1},
+	}
+	for _, test := range tests {
+		actual, err := chart.Effectiveness(test.attacking, test.defending...)
+		if err != nil || actual != test.expected {
+			t.Errorf("Effectiveness(%s, %v) = %v, %v, want %v", test.attacking, test.defending, actual, err, test.expected)
+		}
+	}
+
+	weaknesses, err := chart.Weaknesses("dragon", "flying")
+	if err != nil {
+		t.Fatalf("Failed to get weaknesses: %v", err)
+	}
+	if len(weaknesses[4]) != 1 || weaknesses[4][0] != "ice" || len(weaknesses[1]) != 0 {
+		t.Errorf("Expected only ice at 4x and no neutral entries, got %v", weaknesses)
+	}
+	if _, err := chart.Effectiveness("cosmic", "water"); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+// TestLearnset tests filtering and sorting the moves of a Pokemon
+func TestLearnset(t *testing.T) {
+	var pokemon Pokemon
+	err := json.Unmarshal([]byte(`{"name": "pikachu", "moves": [
+		{"move": {"name": "thunderbolt"}, "version_group_details": [
+			{"level_learned_at": 26, "move_learn_method": {"name": "level-up"}, "version_group": {"name": "red-blue"}},
+			{"level_learned_at": 0, "move_learn_method": {"name": "machine"}, "version_group": {"name": "red-blue"}},
+			{"level_learned_at": 36, "move_learn_method": {"name": "level-up"}, "version_group": {"name": "scarlet-violet"}}
+		]},
+		{"move": {"name": "thunder-shock"}, "version_group_details": [
+			{"level_learned_at": 1, "move_learn_method": {"name": "level-up"}, "version_group": {"name": "red-blue"}}
+		]},
+		{"move": {"name": "volt-tackle"}, "version_group_details": [
+			{"level_learned_at": 0, "move_learn_method": {"name": "egg"}, "version_group": {"name": "scarlet-violet"}}
+		]}
+	]}`), &pokemon)
+	if err != nil {
+		t.Fatalf("Failed to decode pokemon: %v", err)
+	}
+
+	redBlue := pokemon.Learnset("level-up", "red-blue")
+	if len(redBlue) != 2 || redBlue[0].Move != "thunder-shock" || redBlue[1].Level != 26 {
+		t.Errorf("Expected thunder-shock then thunderbolt at 26 in red-blue, got %+v", redBlue)
+	}
+
+	latest := pokemon.Learnset("", "")
+	expected := []LearnsetEntry{
+		{Move: "thunder-shock", Method: "level-up", Level: 1, VersionGroup: "red-blue"},
+		{Move: "thunderbolt", Method: "level-up", Level: 36, VersionGroup: "scarlet-violet"},
+		{Move: "volt-tackle", Method: "egg", VersionGroup: "scarlet-violet"},
+		{Move: "thunderbolt", Method: "machine", VersionGroup: "red-blue"},
+	}
+	if len(latest) != len(expected) {
+		t.Fatalf("Learnset() = %+v, want %+v", latest, expected)
+	}
+	for i := range expected {
+		if latest[i] != expected[i] {
+			t.Errorf("Learnset()[%d] = %+v, want %+v", i, latest[i], expected[i])
+		}
+	}
+}
+
+// TestAbility tests decoding an ability and falling back to flavor text for its short effect
+func TestAbility(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ability/static":
+			w.Write([]byte(`{"id": 9, "name": "static", "generation": {"name": "generation-iii"},
+				"effect_entries": [{"effect": "Whenever a move makes contact...", "short_effect": "Has a 30% chance of paralyzing attacking Pokemon on contact.", "language": {"name": "en"}}],
+				"pokemon": [{"is_hidden": false, "slot": 1, "pokemon": {"name": "pikachu"}}, {"is_hidden": true, "slot": 3, "pokemon": {"name": "electrike"}}]}`))
+		default:
+			w.Write([]byte(`{"id": 310, "name": "toxic-chain", "effect_entries": [],
+				"flavor_text_entries": [{"flavor_text": "The power of the Pokemon's\ntoxic chain may badly\npoison targets.", "language": {"name": "en"}}]}`))
+		}
+	})
+
+	static, err := service.GetAbilityContext(context.Background(), "static")
+	if err != nil {
+		t.Fatalf("Failed to get ability: %v", err)
+	}
+	if static.ShortEffect("en") != "Has a 30% chance of paralyzing attacking Pokemon on contact." {
+		t.Errorf("Unexpected short effect %q", static.ShortEffect("en"))
+	}
+	if len(static.Pokemon) != 2 || !static.Pokemon[1].IsHidden {
+		t.Errorf("Expected electrike to have static as a hidden ability, got %+v", static.Pokemon)
+	}
+
+	toxicChain, err := service.GetAbilityContext(context.Background(), "toxic-chain")
+	if err != nil {
+		t.Fatalf("Failed to get ability: %v", err)
+	}
+	if toxicChain.ShortEffect("en") != "The power of the Pokemon's toxic chain may badly poison targets." {
+		t.Errorf("Unexpected fallback short effect %q", toxicChain.ShortEffect("en"))
+	}
+}
+
+// TestItemsAndBerries tests decoding items, item categories and berries
+func TestItemsAndBerries(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/item/master-ball":
+			w.Write([]byte(`{"id": 1, "name": "master-ball", "cost": 0, "fling_power": null,
+				"attributes": [{"name": "countable"}, {"name": "usable-in-battle"}], "category": {"name": "standard-balls"},
+				"effect_entries": [{"effect": "Catches a wild Pokemon every time.", "short_effect": "Catches a wild Pokemon every time.", "language": {"name": "en"}}]}`))
+		case "/item-category/standard-balls":
+			w.Write([]byte(`{"id": 34, "name": "standard-balls", "pocket": {"name": "pokeballs"}, "items": [{"name": "master-ball"}, {"name": "poke-ball"}]}`))
+		case "/berry/cheri":
+			w.Write([]byte(`{"id": 1, "name": "cheri", "growth_time": 3, "max_harvest": 5, "natural_gift_power": 60,
+				"firmness": {"name": "soft"}, "item": {"name": "cheri-berry"}, "natural_gift_type": {"name": "fire"},
+				"flavors": [{"potency": 10, "flavor": {"name": "spicy"}}, {"potency": 0, "flavor": {"name": "dry"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	item, err := service.GetItemContext(context.Background(), "master-ball")
+	if err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+	if item.FlingPower != nil || len(item.Attributes) != 2 || item.ShortEffect("en") != "Catches a wild Pokemon every time." {
+		t.Errorf("Unexpected item decoded: %+v", item)
+	}
+
+	category, err := service.GetItemCategoryContext(context.Background(), "standard-balls")
+	if err != nil {
+		t.Fatalf("Failed to get item category: %v", err)
+	}
+	if category.Pocket.Name != "pokeballs" || len(category.Items) != 2 {
+		t.Errorf("Unexpected item category decoded: %+v", category)
+	}
+
+	berry, err := service.GetBerryContext(context.Background(), "cheri")
+	if err != nil {
+		t.Fatalf("Failed to get berry: %v", err)
+	}
+	if berry.GrowthTime != 3 || berry.Item.Name != "cheri-berry" || berry.Flavors[0].Flavor.Name != "spicy" {
+		t.Errorf("Unexpected berry decoded: %+v", berry)
+	}
+}
+
+// TestRegionsAndLocations tests walking down from a region to the areas of its locations
+func TestRegionsAndLocations(t *testing.T) {
+	service := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/region/kanto":
+			w.Write([]byte(`{"id": 1, "name": "kanto", "main_generation": {"name": "generation-i"},
+				"locations": [{"name": "pallet-town"}, {"name": "kanto-route-1"}], "version_groups": [{"name": "red-blue"}]}`))
+		case "/location/kanto-route-1":
+			w.Write([]byte(`{"id": 88, "name": "kanto-route-1", "region": {"name": "kanto"}, "areas": [{"name": "kanto-route-1-area"}]}`))
+		case "/generation/generation-i":
+			w.Write([]byte(`{"id": 1, "name": "generation-i", "main_region": {"name": "kanto"}, "pokemon_species": [{"name": "bulbasaur"}, {"name": "mew"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	region, err := service.GetRegionContext(context.Background(), "kanto")
+	if err != nil {
+		t.Fatalf("Failed to get region: %v", err)
+	}
+	if len(region.Locations) != 2 || region.MainGeneration.Name != "generation-i" {
+		t.Errorf("Unexpected region decoded: %+v", region)
+	}
+
+	location, err := service.GetLocationContext(context.Background(), region.Locations[1].Name)
+	if err != nil {
+		t.Fatalf("Failed to get location: %v", err)
+	}
+	if location.Region.Name != "kanto" || len(location.Areas) != 1 || location.Areas[0].Name != "kanto-route-1-area" {
+		t.Errorf("Unexpected location decoded: %+v", location)
+	}
+
+	generation, err := service.GetGenerationContext(context.Background(), region.MainGeneration.Name)
+	if err != nil {
+		t.Fatalf("Failed to get generation: %v", err)
+	}
+	if generation.MainRegion.Name != "kanto" || len(generation.PokemonSpecies) != 2 {
+		t.Errorf("Unexpected generation decoded: %+v", generation)
+	}
+}
+
+// TestEncounters tests merging encounter slots and filtering them by version
+func TestEncounters(t *testing.T) {
+	var area LocationArea
+	err := json.Unmarshal([]byte(`{"name": "kanto-route-1-area", "pokemon_encounters": [
+		{"pokemon": {"name": "pidgey"}, "version_details": [
+			{"version": {"name": "red"}, "max_chance": 70, "encounter_details": [
+				{"min_level": 2, "max_level": 2, "chance": 35, "method": {"name": "walk"}, "condition_values": []},
+				{"min_level": 3, "max_level": 5, "chance": 35, "method": {"name": "walk"}, "condition_values": []}
+			]},
+			{"version": {"name": "heartgold"}, "max_chance": 30, "encounter_details": [
+				{"min_level": 2, "max_level": 4, "chance": 30, "method": {"name": "walk"}, "condition_values": [{"name": "time-morning"}]}
+			]}
+		]},
+		{"pokemon": {"name": "rattata"}, "version_details": [
+			{"version": {"name": "red"}, "max_chance": 30, "encounter_details": [
+				{"min_level": 2, "max_level": 4, "chance": 30, "method": {"name": "walk"}, "condition_values": []}
+			]}
+		]}
+	]}`), &area)
+	if err != nil {
+		t.Fatalf("Failed to decode location area: %v", err)
+	}
+
+	red := area.Encounters("red")
+	if len(red) != 2 {
+		t.Fatalf("Expected 2 encounters in red, got %+v", red)
+	}
+	if red[0].Pokemon != "pidgey" || red[0].MinLevel != 2 || red[0].MaxLevel != 5 || red[0].Chance != 70 {
+		t.Errorf("Expected pidgey slots merged into levels 2-5 at 70%%, got %+v", red[0])
+	}
+
+	heartgold := area.Encounters("heartgold")
+	if len(heartgold) != 1 || len(heartgold[0].Conditions) != 1 || heartgold[0].Conditions[0] != "time-morning" {
+		t.Errorf("Expected a morning-only pidgey in heartgold, got %+v", heartgold)
+	}
+
+	if all := area.Encounters(""); len(all) != 3 {
+		t.Errorf("Expected 3 encounters across versions, got %+v", all)
+	}
+	if versions := area.Versions(); len(versions) != 2 || versions[0] != "heartgold" {
+		t.Errorf("Expected heartgold and red, got %v", versions)
+	}
+}
+
+// TestRollEncounter tests the encounter rate roll, the weighted slot pick and the level range
+func TestRollEncounter(t *testing.T) {
+	var area LocationArea
+	err := json.Unmarshal([]byte(`{"name": "kanto-route-1-area",
+		"encounter_method_rates": [
+			{"encounter_method": {"name": "walk"}, "version_details": [{"rate": 25, "version": {"name": "red"}}]}
+		],
+		"pokemon_encounters": [
+			{"pokemon": {"name": "pidgey"}, "version_details": [
+				{"version": {"name": "red"}, "max_chance": 70, "encounter_details": [
+					{"min_level": 3, "max_level": 5, "chance": 70, "method": {"name": "walk"}, "condition_values": []}
+				]}
+			]},
+			{"pokemon": {"name": "rattata"}, "version_details": [
+				{"version": {"name": "red"}, "max_chance": 30, "encounter_details": [
+					{"min_level": 2, "max_level": 4, "chance": 30, "method": {"name": "walk"}, "condition_values": []}
+				]}
+			]}
+		]}`), &area)
+	if err != nil {
+		t.Fatalf("Failed to decode location area: %v", err)
+	}
+
+	// rolls returns the given numbers in order, one per call to intn
+	rolls := func(numbers ...int) func(int) int {
+		return func(n int) int {
+			next := numbers[0]
+			numbers = numbers[1:]
+			return next
+		}
+	}
+
+	if _, found := area.RollEncounter("walk", "", rolls(25)); found {
+		t.Error("Expected no encounter when the roll misses the 25% rate")
+	}
+	if _, found := area.RollEncounter("surf", "", rolls(0)); found {
+		t.Error("Expected no encounter for a method the area does not have")
+	}
+
+	wild, found := area.RollEncounter("walk", "", rolls(24, 70, 2))
+	if !found {
+		t.Fatal("Expected an encounter when the roll hits the rate")
+	}
+	if wild.Name != "rattata" || wild.Level != 4 || wild.Version != "red" || wild.Area != "kanto-route-1-area" {
+		t.Errorf("Expected a level 4 rattata in red, got %+v", wild)
+	}
+
+	wild, _ = area.RollEncounter("walk", "red", rolls(0, 69, 0))
+	if wild.Name != "pidgey" || wild.Level != 3 {
+		t.Errorf("Expected a level 3 pidgey, got %+v", wild)
+	}
+}
+
+// TestRegionNeighbours tests which locations count as adjacent
+func TestRegionNeighbours(t *testing.T) {
+	// in id order like PokeAPI lists them, which says nothing about how they connect
+	region := Region{Name: "kanto", Locations: []NamedAPIResource{
+		{Name: "viridian-city"}, {Name: "pallet-town"}, {Name: "kanto-sea-route-21"}, {Name: "mt-moon"},
+		{Name: "kanto-route-1"}, {Name: "kanto-route-26"},
+	}}
+
+	neighbours, known := region.Neighbours("pallet-town")
+	if !known || !slices.Equal(neighbours, []string{"kanto-route-1", "kanto-sea-route-21"}) {
+		t.Errorf("Expected kanto-route-1 and kanto-sea-route-21, got %v, %v", neighbours, known)
+	}
+	// connections go both ways and skip locations the region does not list
+	neighbours, known = region.Neighbours("kanto-route-1")
+	if !known || !slices.Equal(neighbours, []string{"pallet-town", "viridian-city"}) {
+		t.Errorf("Expected pallet-town and viridian-city, got %v, %v", neighbours, known)
+	}
+	if neighbours, known := region.Neighbours("kanto-route-26"); known {
+		t.Errorf("Expected kanto-route-26 to be missing from the route map, got %v", neighbours)
+	}
+
+	if !region.Adjacent("pallet-town", "pallet-town") || !region.Adjacent("viridian-city", "kanto-route-1") {
+		t.Error("Expected the same and connected locations to be adjacent")
+	}
+	if region.Adjacent("pallet-town", "viridian-city") || region.Adjacent("pallet-town", "mt-moon") {
+		t.Error("Expected pallet-town to connect to neither viridian-city nor mt-moon")
+	}
+	if !region.Adjacent("kanto-route-26", "mt-moon") || !region.Adjacent("pallet-town", "kanto-route-26") {
+		t.Error("Expected a location without connections to be in reach of every location")
+	}
+	if region.Adjacent("pallet-town", "cerulean-city") {
+		t.Error("Expected a location the region does not list not to be adjacent")
+	}
+
+	johto := Region{Name: "johto", Locations: []NamedAPIResource{{Name: "new-bark-town"}, {Name: "goldenrod-city"}}}
+	if _, known := johto.Neighbours("new-bark-town"); known || !johto.Adjacent("new-bark-town", "goldenrod-city") {
+		t.Error("Expected a region without a route map to let the player go anywhere in it")
+	}
+}
+
+// TestCaptureFormula tests the catch value, ball and status modifiers and the shake checks
+func TestCaptureFormula(t *testing.T) {
+	// a full HP Pokemon with capture rate 45 and a poke-ball has a = 15, about 5.9% to be caught
+	starter := CaptureAttempt{CaptureRate: 45}
+	if a := starter.CatchValue(); a != 15 {
+		t.Errorf("Expected a catch value of 15, got %d", a)
+	}
+	if b := starter.ShakeProbability(); b != 32274 {
+		t.Errorf("Expected a shake probability of 32274, got %d", b)
+	}
+	if chance := starter.Chance(); chance < 0.058 || chance > 0.06 {
+		t.Errorf("Expected about 5.9%% chance, got %f", chance)
+	}
+
+	weakened := CaptureAttempt{CaptureRate: 45, HP: 0.01, Status: "sleep", Ball: "ultra-ball"}
+	if a := weakened.CatchValue(); a != 223 {
+		t.Errorf("Expected a catch value of 223 at 1%% HP, asleep, with an ultra-ball, got %d", a)
+	}
+
+	modifiers := []struct {
+		attempt  CaptureAttempt
+		expected float64
+	}{
+		{CaptureAttempt{Ball: "great-ball"}, 1.5},
+		{CaptureAttempt{Ball: "net-ball", Types: []string{"water"}}, 3},
+		{CaptureAttempt{Ball: "net-ball", Types: []string{"fire"}}, 1},
+		{CaptureAttempt{Ball: "dusk-ball", Night: true}, 3.5},
+		{CaptureAttempt{Ball: "quick-ball", Turn: 1}, 5},
+		{CaptureAttempt{Ball: "quick-ball", Turn: 2}, 1},
+		{CaptureAttempt{Ball: "nest-ball", Level: 11}, 3},
+		{CaptureAttempt{Ball: "repeat-ball", Caught: true}, 3},
+		{CaptureAttempt{Ball: "timer-ball", Turn: 21}, 4},
+	}
+	for _, m := range modifiers {
+		if modifier := m.attempt.BallModifier(); modifier != m.expected {
+			t.Errorf("Expected a %s modifier of %v for %+v, got %v", m.attempt.Ball, m.expected, m.attempt, modifier)
+		}
+	}
+
+	// rolls below b pass a shake check, rolls at or above it fail
+	rolls := func(numbers ...int) func(int) int {
+		return func(n int) int {
+			next := numbers[0]
+			numbers = numbers[1:]
+			return next
+		}
+	}
+	if shakes := starter.Throw(rolls(0, 32273, 32274)); shakes != 2 {
+		t.Errorf("Expected 2 shakes, got %d", shakes)
+	}
+	if shakes := starter.Throw(rolls(0, 0, 0, 0)); shakes != 4 {
+		t.Errorf("Expected a catch after 4 passed checks, got %d", shakes)
+	}
+
+	legendary := CaptureAttempt{CaptureRate: 3, Ball: "master-ball"}
+	if shakes := legendary.Throw(nil); shakes != 4 || legendary.Chance() != 1 {
+		t.Errorf("Expected the master-ball to always catch, got %d shakes", shakes)
+	}
+}
+
+// TestSaveRoundTrip tests that a saved game loads back and carries on with the same random numbers
+func TestSaveRoundTrip(t *testing.T) {
+	state := &CliState{
+		Pokedex:         map[string]PokedexEntry{"pidgey": {Pokemon: Pokemon{Name: "pidgey"}, Level: 3}},
+		Inventory:       map[string]int{"poke-ball": 12},
+		CurrentLocation: "kanto-route-1",
+		CurrentArea:     "kanto-route-1-area",
+	}
+	state.SetSeed(42)
+	state.Rand.IntN(100)
+
+	save, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to snapshot state: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "pokego", "save.json")
+	if err := WriteSave(path, save); err != nil {
+		t.Fatalf("Failed to write save: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".save-*")); len(leftovers) != 0 {
+		t.Errorf("Expected no temporary files left behind, got %v", leftovers)
+	}
+
+	loaded, err := ReadSave(path)
+	if err != nil {
+		t.Fatalf("Failed to read save: %v", err)
+	}
+	restored := &CliState{}
+	if err := restored.Restore(loaded); err != nil {
+		t.Fatalf("Failed to restore state: %v", err)
+	}
+	if restored.Pokedex["pidgey"].Level != 3 || restored.Inventory["poke-ball"] != 12 || restored.CurrentArea != "kanto-route-1-area" || restored.Seed != 42 {
+		t.Errorf("Unexpected restored state: %+v", restored)
+	}
+	for range 5 {
+		if expected, actual := state.Rand.IntN(1000), restored.Rand.IntN(1000); expected != actual {
+			t.Fatalf("Expected the restored random numbers to continue with %d, got %d", expected, actual)
+		}
+	}
+}
+
+// TestSaveMigrations tests upgrading old save files and rejecting unknown ones
+func TestSaveMigrations(t *testing.T) {
+	if SaveVersion != len(saveMigrations)+1 {
+		t.Fatalf("SaveVersion is %d but saveMigrations only reach version %d", SaveVersion, len(saveMigrations)+1)
+	}
+
+	migrations := []saveMigration{
+		// version 1 to 2: current_area is renamed to area
+		func(save map[string]json.RawMessage) error {
+			save["area"] = save["current_area"]
+			delete(save, "current_area")
+			return nil
+		},
+	}
+
+	save := map[string]json.RawMessage{"version": json.RawMessage(`1`), "current_area": json.RawMessage(`"kanto-route-1-area"`)}
+	if err := migrateSave(save, migrations, 2); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if string(save["version"]) != "2" || string(save["area"]) != `"kanto-route-1-area"` {
+		t.Errorf("Expected a version 2 save with area, got %s", save)
+	}
+
+	if err := migrateSave(map[string]json.RawMessage{"version": json.RawMessage(`3`)}, migrations, 2); err == nil {
+		t.Error("Expected an error for a save file newer than the target version")
+	}
+	if err := migrateSave(map[string]json.RawMessage{"version": json.RawMessage(`1`)}, migrations, 3); err == nil {
+		t.Error("Expected an error for a target version without a migration")
+	}
+
+	path := filepath.Join(t.TempDir(), "save.json")
+	if err := os.WriteFile(path, []byte(`{"pokedex": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSave(path); err == nil {
+		t.Error("Expected an error for a file without a version")
+	}
+}
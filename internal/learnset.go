@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+)
+
+// LearnsetEntry is one way a Pokemon learns a move
+type LearnsetEntry struct {
+	Move         string
+	Method       string // level-up, machine, egg, tutor...
+	Level        int    // 0 unless learned by level-up
+	VersionGroup string
+}
+
+// Learnset lists the moves of a Pokemon, optionally limited to one learn method and one version group.
+// Without a version group, each move and method appears once, as learned in the most recent version group.
+// Level-up moves come first in level order, the other methods follow alphabetically.
+func (p Pokemon) Learnset(method, versionGroup string) []LearnsetEntry {
+	var learnset []LearnsetEntry
+	for _, move := range p.Moves {
+		latest := make(map[string]LearnsetEntry)
+		var methods []string
+		for _, detail := range move.VersionGroupDetails {
+			if method != "" && detail.MoveLearnMethod.Name != method {
+				continue
+			}
+			if versionGroup != "" && detail.VersionGroup.Name != versionGroup {
+				continue
+			}
+			entry := LearnsetEntry{
+				Move:         move.Move.Name,
+				Method:       detail.MoveLearnMethod.Name,
+				Level:        detail.LevelLearnedAt,
+				VersionGroup: detail.VersionGroup.Name,
+			}
+			if versionGroup != "" {
+				learnset = append(learnset, entry)
+				continue
+			}
+			// PokeAPI lists version groups oldest first
+			if _, seen := latest[entry.Method]; !seen {
+				methods = append(methods, entry.Method)
+			}
+			latest[entry.Method] = entry
+		}
+		for _, m := range methods {
+			learnset = append(learnset, latest[m])
+		}
+	}
+
+	slices.SortStableFunc(learnset, func(a, b LearnsetEntry) int {
+		if a.Method != b.Method {
+			switch {
+			case a.Method == "level-up":
+				return -1
+			case b.Method == "level-up":
+				return 1
+			}
+			return cmp.Compare(a.Method, b.Method)
+		}
+		return cmp.Or(cmp.Compare(a.Level, b.Level), cmp.Compare(a.Move, b.Move))
+	})
+	return learnset
+}
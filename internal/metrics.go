@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics counts what a PokeAPIService did since it was created
+type Metrics struct {
+	Requests     int64 // HTTP attempts sent, retries included
+	Retries      int64
+	CacheHits    int64 // lookups served by the cache without a request
+	Errors       int64 // calls that returned an error to the caller
+	Throttled    int64 // attempts that had to wait for the rate limiter
+	ThrottleWait time.Duration
+}
+
+type serviceMetrics struct {
+	requests     atomic.Int64
+	retries      atomic.Int64
+	cacheHits    atomic.Int64
+	errors       atomic.Int64
+	throttled    atomic.Int64
+	throttleWait atomic.Int64
+}
+
+// Metrics returns a snapshot of the service's counters
+func (s *PokeAPIService) Metrics() Metrics {
+	return Metrics{
+		Requests:     s.metrics.requests.Load(),
+		Retries:      s.metrics.retries.Load(),
+		CacheHits:    s.metrics.cacheHits.Load(),
+		Errors:       s.metrics.errors.Load(),
+		Throttled:    s.metrics.throttled.Load(),
+		ThrottleWait: time.Duration(s.metrics.throttleWait.Load()),
+	}
+}
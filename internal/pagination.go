@@ -0,0 +1,37 @@
+package internal
+
+// Page is one page of a PokeAPI list endpoint such as /location-area?limit=20&offset=40
+type Page[T any] struct {
+	Count    int    `json:"count"`
+	Next     string `json:"next"`
+	Previous string `json:"previous"`
+	Results  []T    `json:"results"`
+
+	// not part of the API response, filled in from the request
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// PageNumber is the 1-based number of this page
+func (p Page[T]) PageNumber() int {
+	if p.Limit <= 0 {
+		return 1
+	}
+	return p.Offset/p.Limit + 1
+}
+
+// TotalPages is how many pages of this size the whole list spans
+func (p Page[T]) TotalPages() int {
+	if p.Limit <= 0 {
+		return 1
+	}
+	return max(1, (p.Count+p.Limit-1)/p.Limit)
+}
+
+func (p Page[T]) HasNext() bool {
+	return p.Next != ""
+}
+
+func (p Page[T]) HasPrevious() bool {
+	return p.Previous != ""
+}
@@ -1,60 +1,422 @@
 package pokecache
 
 import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
 
 type Cache struct {
-	Entries      map[string]CacheEntry
+	entries      map[string]cacheEntry // every entry has its element in recency
 	ReapInterval time.Duration
 	mu           sync.Mutex
+
+	maxEntries int        // 0 means no limit
+	maxBytes   int        // 0 means no limit
+	bytes      int        // size of every key and value held
+	recency    *list.List // keys, most recently used at the front
+	onEvict    func(key string, val []byte)
+
+	namespaceTTLs map[string]time.Duration // TTL by key prefix, ReapInterval for keys without one
+	staleFor      time.Duration            // how long past its TTL an entry is still served while it is refreshed
+	refresh       func(ctx context.Context, key string) ([]byte, error)
+	refreshing    map[string]bool
+	refreshes     sync.WaitGroup
+	refreshCtx    context.Context // passed to refresh, cancelled by Close
+	stopRefreshes context.CancelFunc
+	now           func() time.Time
+
+	loads       map[string]*load // loads in flight, shared by every GetOrLoad of the same key
+	negatives   map[string]negativeEntry
+	negativeTTL time.Duration
+	isNegative  func(err error) bool
+
+	ctx       context.Context // stops the reap loop like Close when it is done
+	done      chan struct{}   // closed by Close
+	closeOnce sync.Once
+	stopped   chan struct{} // closed when the reap loop has returned
 }
 
-type CacheEntry struct {
+type cacheEntry struct {
 	Timestamp time.Time
 	EntryData []byte
+	TTL       time.Duration
+	element   *list.Element
+}
+
+// load is a GetOrLoad in flight, done is closed once val and err are set
+type load struct {
+	done chan struct{}
+	val  []byte
+	err  error
+}
+
+// negativeEntry remembers a load that failed, e.g. because the resource does not exist
+type negativeEntry struct {
+	err     error
+	expires time.Time
+}
+
+// CacheOption configures a Cache
+type CacheOption func(*Cache)
+
+// WithMaxEntries keeps at most maxEntries entries, evicting the least recently used ones first
+func WithMaxEntries(maxEntries int) CacheOption {
+	return func(c *Cache) {
+		c.maxEntries = maxEntries
+	}
+}
+
+// WithMaxBytes keeps the keys and values under maxBytes, evicting the least recently used entries first.
+// A single value bigger than the budget is not cached at all.
+func WithMaxBytes(maxBytes int) CacheOption {
+	return func(c *Cache) {
+		c.maxBytes = maxBytes
+	}
 }
 
-func NewCache(reapInterval time.Duration) (*Cache, error) {
+// WithEvictionCallback is called with every entry that is evicted or reaped, outside of the cache lock
+func WithEvictionCallback(onEvict func(key string, val []byte)) CacheOption {
+	return func(c *Cache) {
+		c.onEvict = onEvict
+	}
+}
+
+// WithNamespaceTTL gives keys starting with prefix their own TTL, e.g. longer for resources that never change.
+// When several prefixes match a key the longest one wins.
+func WithNamespaceTTL(prefix string, ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		if c.namespaceTTLs == nil {
+			c.namespaceTTLs = make(map[string]time.Duration)
+		}
+		c.namespaceTTLs[prefix] = ttl
+	}
+}
+
+// WithStaleWhileRevalidate keeps serving an expired entry for up to staleFor past its TTL.
+// The first Get of a stale entry calls refresh in the background and stores what it returns;
+// when refresh fails the stale value keeps being served until staleFor is over.
+// The ctx given to refresh is cancelled by Close and when the cache's context is done.
+func WithStaleWhileRevalidate(staleFor time.Duration, refresh func(ctx context.Context, key string) ([]byte, error)) CacheOption {
+	return func(c *Cache) {
+		c.staleFor = staleFor
+		c.refresh = refresh
+	}
+}
+
+// WithNegativeCaching remembers for ttl the errors of GetOrLoad that isNegative accepts, such as a 404,
+// so looking up a missing resource again fails right away instead of asking for it again
+func WithNegativeCaching(ttl time.Duration, isNegative func(err error) bool) CacheOption {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+		c.isNegative = isNegative
+	}
+}
+
+// WithContext stops reaping when ctx is done, as if Close was called
+func WithContext(ctx context.Context) CacheOption {
+	return func(c *Cache) {
+		c.ctx = ctx
+	}
+}
+
+// NewCache starts a cache whose entries expire after reapInterval unless they have their own TTL.
+// Close it to stop its reap goroutine.
+func NewCache(reapInterval time.Duration, opts ...CacheOption) (*Cache, error) {
+	if reapInterval <= 0 {
+		return nil, fmt.Errorf("reap interval must be positive, got %s", reapInterval)
+	}
+
 	cache := Cache{
-		Entries:      make(map[string]CacheEntry),
+		entries:      make(map[string]cacheEntry),
 		ReapInterval: reapInterval,
+		recency:      list.New(),
+		refreshing:   make(map[string]bool),
+		loads:        make(map[string]*load),
+		negatives:    make(map[string]negativeEntry),
+		now:          time.Now,
+		ctx:          context.Background(),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&cache)
 	}
+	cache.refreshCtx, cache.stopRefreshes = context.WithCancel(cache.ctx)
 
 	go cache.reapLoop()
 
 	return &cache, nil
 }
 
+// Close stops the reap goroutine, cancels any background refresh and waits for them to return.
+// The cache stays usable, expired entries are just no longer reaped or refreshed. Closing more than once is a no-op.
+func (c *Cache) Close() error {
+	c.closeOnce.Do(func() {
+		// under the lock, so no revalidate can start a refresh once Wait may run
+		c.mu.Lock()
+		close(c.done)
+		c.stopRefreshes()
+		c.mu.Unlock()
+	})
+	<-c.stopped
+	c.refreshes.Wait()
+	return nil
+}
+
 func (c *Cache) Get(key string) ([]byte, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.get(key)
+}
 
-	entry, ok := c.Entries[key]
-	return entry.EntryData, ok
+// get is Get with c.mu held
+func (c *Cache) get(key string) ([]byte, bool) {
+	entry, ok := c.entries[key]
+	if !ok || c.reapable(entry) {
+		// expired entries are misses even before the reap loop gets to them
+		return nil, false
+	}
+	if c.expired(entry) {
+		c.revalidate(key)
+	}
+	c.recency.MoveToFront(entry.element)
+	return entry.EntryData, true
 }
 
+// GetOrLoad returns the entry for key, calling loader to fill it on a miss.
+// Concurrent calls for the same key share a single loader call and all get its value or error.
+func (c *Cache) GetOrLoad(key string, loader func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if val, ok := c.get(key); ok {
+		c.mu.Unlock()
+		return val, nil
+	}
+	if negative, ok := c.negatives[key]; ok && c.now().Before(negative.expires) {
+		c.mu.Unlock()
+		return nil, negative.err
+	}
+	if inFlight, ok := c.loads[key]; ok {
+		c.mu.Unlock()
+		<-inFlight.done
+		return inFlight.val, inFlight.err
+	}
+	l := &load{done: make(chan struct{})}
+	c.loads[key] = l
+	c.mu.Unlock()
+
+	l.val, l.err = callLoader(loader)
+
+	c.mu.Lock()
+	delete(c.loads, key)
+	var evicted []evictedEntry
+	if l.err == nil {
+		delete(c.negatives, key)
+		evicted = c.add(key, l.val, c.namespaceTTL(key))
+	} else if c.isNegative != nil && c.isNegative(l.err) {
+		c.negatives[key] = negativeEntry{err: l.err, expires: c.now().Add(c.negativeTTL)}
+	}
+	c.mu.Unlock()
+	close(l.done)
+
+	c.notify(evicted)
+	return l.val, l.err
+}
+
+// callLoader turns a panic of loader into an error, so the load is always cleaned up and waiters never hang
+func callLoader(loader func() ([]byte, error)) (val []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			val, err = nil, fmt.Errorf("cache loader panicked: %v", r)
+		}
+	}()
+	return loader()
+}
+
+// Add stores the entry with the TTL of its namespace
 func (c *Cache) Add(key string, val []byte) {
+	c.AddWithTTL(key, val, 0)
+}
+
+// AddWithTTL stores the entry with its own TTL, a non-positive ttl uses the TTL of its namespace
+func (c *Cache) AddWithTTL(key string, val []byte, ttl time.Duration) {
+	c.mu.Lock()
+	if ttl <= 0 {
+		ttl = c.namespaceTTL(key)
+	}
+	evicted := c.add(key, val, ttl)
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// TTLFor returns the TTL an entry added under key gets, from its namespace or ReapInterval
+func (c *Cache) TTLFor(key string) time.Duration {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.namespaceTTL(key)
+}
 
-	c.Entries[key] = CacheEntry{
-		Timestamp: time.Now(),
-		EntryData: val,
+// namespaceTTL returns the TTL of the longest prefix matching key, c.mu must be held
+func (c *Cache) namespaceTTL(key string) time.Duration {
+	ttl, longest := c.ReapInterval, -1
+	for prefix, prefixTTL := range c.namespaceTTLs {
+		if strings.HasPrefix(key, prefix) && len(prefix) > longest {
+			ttl, longest = prefixTTL, len(prefix)
+		}
 	}
+	return ttl
 }
 
-func (c *Cache) reapLoop() {
-	for {
+// expired reports whether the entry is past its TTL, it may still be served stale
+func (c *Cache) expired(entry cacheEntry) bool {
+	return c.now().Sub(entry.Timestamp) > entry.TTL
+}
+
+// reapable reports whether the entry is past its TTL and cannot be served stale either
+func (c *Cache) reapable(entry cacheEntry) bool {
+	if c.refresh == nil {
+		return c.expired(entry)
+	}
+	return c.now().Sub(entry.Timestamp) > entry.TTL+c.staleFor
+}
+
+// revalidate refreshes a stale entry in the background, once at a time per key and never after Close.
+// c.mu must be held.
+func (c *Cache) revalidate(key string) {
+	if c.refreshing[key] || c.refreshCtx.Err() != nil {
+		return
+	}
+	c.refreshing[key] = true
+	c.refreshes.Add(1)
+
+	go func() {
+		defer c.refreshes.Done()
+		val, err := c.refresh(c.refreshCtx, key)
+
 		c.mu.Lock()
-		for k, v := range c.Entries {
-			// remove the entry if it is older than the reap interval
-			if time.Since(v.Timestamp) > c.ReapInterval {
-				delete(c.Entries, k)
-			}
+		delete(c.refreshing, key)
+		var evicted []evictedEntry
+		if err == nil {
+			evicted = c.add(key, val, c.namespaceTTL(key))
 		}
 		c.mu.Unlock()
-		time.Sleep(c.ReapInterval)
+		c.notify(evicted)
+	}()
+}
+
+// Len returns the number of entries in the cache
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Bytes returns the size of every key and value in the cache
+func (c *Cache) Bytes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// evictedEntry is an entry removed from the cache, waiting to be passed to the eviction callback
+type evictedEntry struct {
+	key string
+	val []byte
+}
+
+// add stores the entry and returns the entries evicted to stay within budget, oldest first. c.mu must be held.
+func (c *Cache) add(key string, val []byte, ttl time.Duration) []evictedEntry {
+	if c.maxBytes > 0 && entrySize(key, val) > c.maxBytes {
+		if _, ok := c.entries[key]; ok {
+			// the old value is stale now, keeping it would be worse than a miss
+			return []evictedEntry{{key, c.remove(key)}}
+		}
+		return nil
+	}
+
+	entry, ok := c.entries[key]
+	if ok {
+		c.bytes -= entrySize(key, entry.EntryData)
+		c.recency.MoveToFront(entry.element)
+	} else {
+		entry.element = c.recency.PushFront(key)
+	}
+	entry.Timestamp = c.now()
+	entry.EntryData = val
+	entry.TTL = ttl
+	c.entries[key] = entry
+	c.bytes += entrySize(key, val)
+
+	var evicted []evictedEntry
+	for c.overBudget() {
+		oldest := c.recency.Back().Value.(string)
+		evicted = append(evicted, evictedEntry{oldest, c.remove(oldest)})
+	}
+	return evicted
+}
+
+func (c *Cache) overBudget() bool {
+	return (c.maxEntries > 0 && len(c.entries) > c.maxEntries) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+// remove deletes an entry that exists and returns its value, c.mu must be held
+func (c *Cache) remove(key string) []byte {
+	entry := c.entries[key]
+	c.recency.Remove(entry.element)
+	delete(c.entries, key)
+	c.bytes -= entrySize(key, entry.EntryData)
+	return entry.EntryData
+}
+
+func (c *Cache) notify(evicted []evictedEntry) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, entry := range evicted {
+		c.onEvict(entry.key, entry.val)
+	}
+}
+
+func entrySize(key string, val []byte) int {
+	return len(key) + len(val)
+}
+
+func (c *Cache) reapLoop() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.ReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.reap()
+		}
+	}
+}
+
+// reap removes every entry that can no longer be served
+func (c *Cache) reap() {
+	c.mu.Lock()
+	var reaped []evictedEntry
+	for k, v := range c.entries {
+		if c.reapable(v) {
+			reaped = append(reaped, evictedEntry{k, c.remove(k)})
+		}
+	}
+	for k, negative := range c.negatives {
+		if !c.now().Before(negative.expires) {
+			delete(c.negatives, k)
+		}
 	}
+	c.mu.Unlock()
+	c.notify(reaped)
 }
@@ -1,7 +1,12 @@
 package pokecache
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -34,6 +39,7 @@ func TestAddGet(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to create cache: %v", err)
 			}
+			defer cache.Close()
 
 			// Add the test data to the cache
 			cache.Add(c.key, c.val)
@@ -65,6 +71,7 @@ func TestReapLoop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create cache: %v", err)
 	}
+	defer cache.Close()
 
 	// Add test data to the cache
 	testKey := "location_areas_0"
@@ -92,3 +99,487 @@ func TestReapLoop(t *testing.T) {
 		return
 	}
 }
+
+// TestLRUEviction tests that the least recently used entries are evicted once the cache is over budget
+func TestLRUEviction(t *testing.T) {
+	var evicted []string
+	cache, err := NewCache(time.Minute, WithMaxEntries(2), WithEvictionCallback(func(key string, val []byte) {
+		evicted = append(evicted, key)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	cache.Add("pokemon_pidgey", []byte("pidgey"))
+	cache.Add("pokemon_rattata", []byte("rattata"))
+	// reading pidgey makes rattata the least recently used entry
+	cache.Get("pokemon_pidgey")
+	cache.Add("pokemon_spearow", []byte("spearow"))
+
+	if _, ok := cache.Get("pokemon_rattata"); ok {
+		t.Error("Expected pokemon_rattata to be evicted")
+	}
+	if _, ok := cache.Get("pokemon_pidgey"); !ok {
+		t.Error("Expected the recently read pokemon_pidgey to stay")
+	}
+	if len(evicted) != 1 || evicted[0] != "pokemon_rattata" {
+		t.Errorf("Expected the callback to get pokemon_rattata, got %v", evicted)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+}
+
+// TestMaxBytes tests that the cache stays within its byte budget
+func TestMaxBytes(t *testing.T) {
+	cache, err := NewCache(time.Minute, WithMaxBytes(30))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	cache.Add("a", []byte("0123456789"))
+	cache.Add("b", []byte("0123456789"))
+	if cache.Bytes() != 22 {
+		t.Errorf("Expected 22 bytes, got %d", cache.Bytes())
+	}
+
+	cache.Add("c", []byte("0123456789"))
+	if _, ok := cache.Get("a"); ok || cache.Bytes() != 22 {
+		t.Errorf("Expected a to be evicted to stay under 30 bytes, got %d bytes", cache.Bytes())
+	}
+
+	// overwriting an entry replaces its size instead of adding to it
+	cache.Add("c", []byte("01234"))
+	if cache.Bytes() != 17 {
+		t.Errorf("Expected 17 bytes after overwriting c, got %d", cache.Bytes())
+	}
+
+	cache.Add("huge", make([]byte, 100))
+	if _, ok := cache.Get("huge"); ok || cache.Len() != 2 {
+		t.Errorf("Expected a value bigger than the budget not to be cached, got %d entries", cache.Len())
+	}
+}
+
+// TestClose tests that the reap goroutine stops on Close or when its context is done
+func TestClose(t *testing.T) {
+	if _, err := NewCache(0); err == nil {
+		t.Error("Expected an error for a zero reap interval")
+	}
+
+	cache, err := NewCache(time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	// a second Close must not panic on the closed channel
+	cache.Close()
+	cache.Add("pokemon_pidgey", []byte("pidgey"))
+	if _, ok := cache.Get("pokemon_pidgey"); !ok {
+		t.Error("Expected a closed cache to keep working")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cache, err = NewCache(time.Millisecond, WithContext(ctx))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	cancel()
+	select {
+	case <-cache.stopped:
+	case <-time.After(time.Second):
+		t.Error("Expected the reap loop to stop when its context is cancelled")
+	}
+
+	// Close must not wait for a slow refresh to finish on its own
+	cache, err = NewCache(time.Minute, WithStaleWhileRevalidate(time.Hour, func(ctx context.Context, key string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+	cache.Add("pokemon_pidgey", []byte("pidgey"))
+	clock = clock.Add(2 * time.Minute)
+	cache.Get("pokemon_pidgey")
+	closed := make(chan struct{})
+	go func() {
+		cache.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("Expected Close to cancel the refresh in flight")
+	}
+
+	// a closed cache serves stale entries without starting refreshes
+	cache.Add("pokemon_rattata", []byte("rattata"))
+	clock = clock.Add(2 * time.Minute)
+	if val, ok := cache.Get("pokemon_rattata"); !ok || string(val) != "rattata" {
+		t.Errorf("Expected the stale value after Close, got %q, %v", val, ok)
+	}
+	cache.mu.Lock()
+	refreshing := len(cache.refreshing)
+	cache.mu.Unlock()
+	if refreshing != 0 {
+		t.Errorf("Expected no refresh after Close, got %d", refreshing)
+	}
+}
+
+// TestDiskCache tests that entries survive reopening the cache and expire after their TTL
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir, WithDiskTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create disk cache: %v", err)
+	}
+	cache.Add("pokemon_pidgey", []byte(`{"name": "pidgey"}`))
+	// the disk TTL is also the longest an entry may ask for
+	cache.AddWithTTL("pokemon_rattata", []byte(`{"name": "rattata"}`), 24*time.Hour)
+
+	reopened, err := NewDiskCache(dir, WithDiskTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to reopen disk cache: %v", err)
+	}
+	if val, ok := reopened.Get("pokemon_pidgey"); !ok || string(val) != `{"name": "pidgey"}` {
+		t.Errorf("Expected pokemon_pidgey to survive reopening, got %q, %v", val, ok)
+	}
+	if reopened.Bytes() != cache.Bytes() || reopened.Bytes() == 0 {
+		t.Errorf("Expected the reopened cache to count %d bytes, got %d", cache.Bytes(), reopened.Bytes())
+	}
+	if _, ok := reopened.Get("pokemon_spearow"); ok {
+		t.Error("Expected a miss for a key that was never added")
+	}
+
+	reopened.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	for _, key := range []string{"pokemon_pidgey", "pokemon_rattata"} {
+		if _, ok := reopened.Get(key); ok {
+			t.Errorf("Expected %s to expire after the disk TTL", key)
+		}
+	}
+	if reopened.Bytes() != 0 {
+		t.Errorf("Expected the expired file to be deleted, %d bytes left", reopened.Bytes())
+	}
+}
+
+// TestDiskCacheCorruption tests that a damaged file is a miss and gets deleted
+func TestDiskCacheCorruption(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk cache: %v", err)
+	}
+	cache.Add("pokemon_pidgey", []byte(`{"name": "pidgey"}`))
+
+	path := cache.path("pokemon_pidgey")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read entry file: %v", err)
+	}
+	data[len(data)-2] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to damage entry file: %v", err)
+	}
+
+	if _, ok := cache.Get("pokemon_pidgey"); ok {
+		t.Error("Expected a damaged entry to be a miss")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the damaged file to be deleted, got %v", err)
+	}
+}
+
+// TestDiskCacheMaxBytes tests that the least recently used files are deleted once the directory is over its cap
+func TestDiskCacheMaxBytes(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), WithDiskMaxBytes(250))
+	if err != nil {
+		t.Fatalf("Failed to create disk cache: %v", err)
+	}
+
+	// every entry file is 48 bytes of header, the key and a 50 byte value
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+	for _, key := range []string{"a", "b"} {
+		cache.Add(key, make([]byte, 50))
+		clock = clock.Add(time.Second)
+	}
+	// reading a makes b the least recently used file
+	cache.Get("a")
+	clock = clock.Add(time.Second)
+	cache.Add("c", make([]byte, 50))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("Expected the recently read a to stay")
+	}
+	if cache.Bytes() > 250 {
+		t.Errorf("Expected at most 250 bytes, got %d", cache.Bytes())
+	}
+}
+
+// TestTieredCache tests that a hit in the slower layer fills the faster one
+func TestTieredCache(t *testing.T) {
+	memory, err := NewCache(time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	disk, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create disk cache: %v", err)
+	}
+	cache := NewTieredCache(memory, disk)
+	defer cache.Close()
+
+	disk.Add("pokemon_pidgey", []byte("pidgey"))
+	if val, ok := cache.Get("pokemon_pidgey"); !ok || string(val) != "pidgey" {
+		t.Fatalf("Expected a hit from the disk layer, got %q, %v", val, ok)
+	}
+	if _, ok := memory.Get("pokemon_pidgey"); !ok {
+		t.Error("Expected the disk hit to be copied into memory")
+	}
+
+	cache.Add("pokemon_rattata", []byte("rattata"))
+	if _, ok := disk.Get("pokemon_rattata"); !ok {
+		t.Error("Expected Add to write through to disk")
+	}
+
+	disk.Add("pokemon_spearow", []byte("spearow"))
+	val, err := cache.GetOrLoad("pokemon_spearow", func() ([]byte, error) {
+		t.Error("Expected the disk hit to skip the loader")
+		return nil, nil
+	})
+	if err != nil || string(val) != "spearow" {
+		t.Errorf("Expected spearow from disk, got %q, %v", val, err)
+	}
+	if _, err := cache.GetOrLoad("pokemon_fearow", func() ([]byte, error) { return []byte("fearow"), nil }); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if _, ok := disk.Get("pokemon_fearow"); !ok {
+		t.Error("Expected a loaded value to be written to disk")
+	}
+
+	// the disk keeps its own TTLs, whatever the memory layer gives the same keys
+	memory, err = NewCache(time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer memory.Close()
+	disk, err = NewDiskCache(t.TempDir(), WithDiskTTL(24*time.Hour), WithDiskNamespaceTTL("location_areas_", time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create disk cache: %v", err)
+	}
+	cache = NewTieredCache(memory, disk)
+	clock := time.Now()
+	disk.now = func() time.Time { return clock }
+	for _, key := range []string{"location_areas_0_20", "pokemon_pidgeotto"} {
+		if _, err := cache.GetOrLoad(key, func() ([]byte, error) { return []byte(key), nil }); err != nil {
+			t.Fatalf("Failed to load %s: %v", key, err)
+		}
+	}
+	clock = clock.Add(2 * time.Hour)
+	if _, ok := disk.Get("location_areas_0_20"); ok {
+		t.Error("Expected the list page to expire on disk with its namespace TTL")
+	}
+	if _, ok := disk.Get("pokemon_pidgeotto"); !ok {
+		t.Error("Expected the pokemon to stay on disk for the disk TTL")
+	}
+	clock = clock.Add(24 * time.Hour)
+	if _, ok := disk.Get("pokemon_pidgeotto"); ok {
+		t.Error("Expected the pokemon to expire after the disk TTL")
+	}
+}
+
+// TestEntryTTL tests per-entry and per-namespace TTLs
+func TestEntryTTL(t *testing.T) {
+	cache, err := NewCache(time.Minute,
+		WithNamespaceTTL("location_", time.Hour),
+		WithNamespaceTTL("location_areas_", 2*time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+	cache.Add("pokemon_pidgey", []byte("pidgey"))
+	cache.Add("location_pallet-town", []byte("pallet-town"))
+	cache.Add("location_areas_0_20", []byte("page"))
+	cache.AddWithTTL("names_pokemon", []byte("names"), 10*time.Minute)
+
+	clock = clock.Add(5 * time.Minute)
+	expected := map[string]bool{
+		"pokemon_pidgey":       false, // the one minute ReapInterval
+		"location_pallet-town": true,
+		"location_areas_0_20":  false, // the longer prefix wins over location_
+		"names_pokemon":        true,
+	}
+	for key, hit := range expected {
+		if _, ok := cache.Get(key); ok != hit {
+			t.Errorf("Expected Get(%q) to return %v after 5 minutes, got %v", key, hit, ok)
+		}
+	}
+}
+
+// TestStaleWhileRevalidate tests that an expired entry is served while it is refreshed in the background
+func TestStaleWhileRevalidate(t *testing.T) {
+	var refreshes atomic.Int32
+	var fail atomic.Bool
+	cache, err := NewCache(time.Minute, WithStaleWhileRevalidate(time.Hour, func(ctx context.Context, key string) ([]byte, error) {
+		refreshes.Add(1)
+		if fail.Load() {
+			return nil, errors.New("PokeAPI is down")
+		}
+		return []byte("fresh " + key), nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+	cache.Add("names_pokemon", []byte("stale"))
+
+	clock = clock.Add(2 * time.Minute)
+	if val, ok := cache.Get("names_pokemon"); !ok || string(val) != "stale" {
+		t.Fatalf("Expected the stale value right away, got %q, %v", val, ok)
+	}
+	cache.refreshes.Wait()
+	if val, _ := cache.Get("names_pokemon"); string(val) != "fresh names_pokemon" || refreshes.Load() != 1 {
+		t.Errorf("Expected the refreshed value after one refresh, got %q after %d", val, refreshes.Load())
+	}
+
+	// a failed refresh keeps serving the stale value until staleFor is over
+	fail.Store(true)
+	clock = clock.Add(2 * time.Minute)
+	cache.Get("names_pokemon")
+	cache.refreshes.Wait()
+	if val, ok := cache.Get("names_pokemon"); !ok || string(val) != "fresh names_pokemon" {
+		t.Errorf("Expected the stale value to survive a failed refresh, got %q, %v", val, ok)
+	}
+	cache.refreshes.Wait()
+
+	clock = clock.Add(2 * time.Hour)
+	if _, ok := cache.Get("names_pokemon"); ok {
+		t.Error("Expected a miss once the entry is past its TTL and staleFor")
+	}
+}
+
+// TestGetOrLoad tests that concurrent misses of one key share a single load, its value and its error
+func TestGetOrLoad(t *testing.T) {
+	cache, err := NewCache(time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	for _, loadErr := range []error{nil, errors.New("PokeAPI is down")} {
+		key := fmt.Sprintf("pokemon_pikachu_%v", loadErr)
+		var loads atomic.Int32
+		release := make(chan struct{})
+		loader := func() ([]byte, error) {
+			loads.Add(1)
+			<-release
+			return []byte("pikachu"), loadErr
+		}
+
+		const callers = 10
+		results := make(chan error, callers)
+		for range callers {
+			go func() {
+				val, err := cache.GetOrLoad(key, loader)
+				if err == nil && string(val) != "pikachu" {
+					err = fmt.Errorf("unexpected value %q", val)
+				}
+				results <- err
+			}()
+		}
+		// let every caller reach the cache before the load finishes
+		for loads.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+
+		for range callers {
+			if err := <-results; !errors.Is(err, loadErr) {
+				t.Errorf("Expected every caller to get %v, got %v", loadErr, err)
+			}
+		}
+		if loads.Load() != 1 {
+			t.Errorf("Expected one load for %d callers, got %d", callers, loads.Load())
+		}
+	}
+
+	if _, ok := cache.Get("pokemon_pikachu_<nil>"); !ok {
+		t.Error("Expected a successful load to be cached")
+	}
+	if _, ok := cache.Get("pokemon_pikachu_PokeAPI is down"); ok {
+		t.Error("Expected a failed load not to be cached")
+	}
+
+	// a panicking loader fails its load instead of leaving it in flight forever
+	if _, err := cache.GetOrLoad("pokemon_missingno", func() ([]byte, error) { panic("glitch") }); err == nil {
+		t.Error("Expected an error from a panicking loader")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		val, err := cache.GetOrLoad("pokemon_missingno", func() ([]byte, error) { return []byte("missingno"), nil })
+		if err != nil || string(val) != "missingno" {
+			t.Errorf("Expected the next load to run, got %q, %v", val, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected GetOrLoad not to hang after a loader panicked")
+	}
+}
+
+// TestNegativeCaching tests that errors accepted by the predicate are remembered until their TTL is over
+func TestNegativeCaching(t *testing.T) {
+	errNotFound := errors.New("not found")
+	cache, err := NewCache(time.Minute, WithNegativeCaching(30*time.Second, func(err error) bool {
+		return errors.Is(err, errNotFound)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	clock := time.Now()
+	cache.now = func() time.Time { return clock }
+	var loads int
+	loadErr := errNotFound
+	loader := func() ([]byte, error) {
+		loads++
+		return nil, loadErr
+	}
+
+	cache.GetOrLoad("pokemon_pikachuu", loader)
+	if _, err := cache.GetOrLoad("pokemon_pikachuu", loader); !errors.Is(err, errNotFound) || loads != 1 {
+		t.Errorf("Expected the cached not found error without a second load, got %v after %d loads", err, loads)
+	}
+
+	clock = clock.Add(time.Minute)
+	cache.GetOrLoad("pokemon_pikachuu", loader)
+	if loads != 2 {
+		t.Errorf("Expected a new load once the negative entry expired, got %d loads", loads)
+	}
+
+	loadErr = errors.New("PokeAPI is down")
+	cache.GetOrLoad("pokemon_eevee", loader)
+	cache.GetOrLoad("pokemon_eevee", loader)
+	if loads != 4 {
+		t.Errorf("Expected other errors not to be cached, got %d loads", loads)
+	}
+}
@@ -0,0 +1,303 @@
+package pokecache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDiskTTL      = 7 * 24 * time.Hour
+	DefaultDiskMaxBytes = 256 << 20
+
+	diskEntryExt = ".entry"
+)
+
+// diskMagic starts every entry file, so files from another program or format are never trusted
+var diskMagic = []byte("PKC1")
+
+// DiskCache keeps one file per key in a directory, so cached data survives restarts.
+// Each file holds the key, its expiry and a SHA-256 checksum; expired and corrupted files are misses and get deleted.
+type DiskCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	mu       sync.Mutex
+	bytes    int64 // size of every entry file in dir
+	now      func() time.Time
+
+	namespaceTTLs map[string]time.Duration // TTL by key prefix, ttl for keys without one
+}
+
+// DiskCacheOption configures a DiskCache
+type DiskCacheOption func(*DiskCache)
+
+// WithDiskTTL sets how long entries stay valid at most, DefaultDiskTTL by default
+func WithDiskTTL(ttl time.Duration) DiskCacheOption {
+	return func(c *DiskCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithDiskNamespaceTTL gives keys starting with prefix a shorter TTL on disk, e.g. for lists that grow.
+// When several prefixes match a key the longest one wins, and no TTL is ever longer than the disk's.
+func WithDiskNamespaceTTL(prefix string, ttl time.Duration) DiskCacheOption {
+	return func(c *DiskCache) {
+		if c.namespaceTTLs == nil {
+			c.namespaceTTLs = make(map[string]time.Duration)
+		}
+		c.namespaceTTLs[prefix] = ttl
+	}
+}
+
+// WithDiskMaxBytes caps the size of the cache directory, evicting the least recently used files first.
+// DefaultDiskMaxBytes by default, 0 means no limit.
+func WithDiskMaxBytes(maxBytes int64) DiskCacheOption {
+	return func(c *DiskCache) {
+		c.maxBytes = maxBytes
+	}
+}
+
+// DefaultDiskCacheDir is the pokego directory in $XDG_CACHE_HOME, ~/.cache when unset
+func DefaultDiskCacheDir() (string, error) {
+	cacheHome, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheHome, "pokego"), nil
+}
+
+// NewDiskCache opens or creates a disk cache in dir
+func NewDiskCache(dir string, opts ...DiskCacheOption) (*DiskCache, error) {
+	cache := DiskCache{
+		dir:      dir,
+		ttl:      DefaultDiskTTL,
+		maxBytes: DefaultDiskMaxBytes,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cache)
+	}
+	if cache.ttl <= 0 {
+		return nil, fmt.Errorf("disk cache TTL must be positive, got %s", cache.ttl)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := cache.entryFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		cache.bytes += file.size
+	}
+	return &cache, nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	storedKey, expires, val, err := decodeDiskEntry(data)
+	if err != nil || storedKey != key || !c.now().Before(expires) {
+		c.remove(path, int64(len(data)))
+		return nil, false
+	}
+
+	// the modification time doubles as the last use, which eviction goes by
+	now := c.now()
+	os.Chtimes(path, now, now)
+	return val, true
+}
+
+// Add writes the entry to disk with the TTL of its namespace.
+// The Cache interface has no error, so a failed write only means a later miss.
+func (c *DiskCache) Add(key string, val []byte) {
+	c.AddWithTTL(key, val, 0)
+}
+
+// AddWithTTL writes the entry to disk with its own TTL, a non-positive ttl uses the TTL of its namespace.
+// The disk's TTL caps both.
+func (c *DiskCache) AddWithTTL(key string, val []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = c.namespaceTTL(key)
+	}
+	ttl = min(ttl, c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := c.path(key)
+	var oldSize int64
+	if info, err := os.Stat(path); err == nil {
+		oldSize = info.Size()
+	}
+
+	data := encodeDiskEntry(key, c.now().Add(ttl), val)
+	if c.maxBytes > 0 && int64(len(data)) > c.maxBytes {
+		// too big to cache, and the old value is stale now
+		c.remove(path, oldSize)
+		return
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return
+	}
+	c.bytes += int64(len(data)) - oldSize
+
+	if c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.evict()
+	}
+}
+
+// Bytes returns the size of every entry file
+func (c *DiskCache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// namespaceTTL returns the TTL of the longest prefix matching key, the disk's TTL for keys without one
+func (c *DiskCache) namespaceTTL(key string) time.Duration {
+	ttl, longest := c.ttl, -1
+	for prefix, prefixTTL := range c.namespaceTTLs {
+		if strings.HasPrefix(key, prefix) && len(prefix) > longest {
+			ttl, longest = prefixTTL, len(prefix)
+		}
+	}
+	return ttl
+}
+
+// path names the file of a key after its hash, so any key makes a safe file name
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+diskEntryExt)
+}
+
+func (c *DiskCache) remove(path string, size int64) {
+	if err := os.Remove(path); err == nil {
+		c.bytes -= size
+	}
+}
+
+type diskEntryFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *DiskCache) entryFiles() ([]diskEntryFile, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []diskEntryFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), diskEntryExt) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			// removed since ReadDir
+			continue
+		}
+		files = append(files, diskEntryFile{
+			path:    filepath.Join(c.dir, dirEntry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// evict deletes the least recently used files until the cache fits in maxBytes, c.mu must be held
+func (c *DiskCache) evict() {
+	files, err := c.entryFiles()
+	if err != nil {
+		return
+	}
+	slices.SortFunc(files, func(a, b diskEntryFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	// recount from disk, another process may share the directory
+	c.bytes = 0
+	for _, file := range files {
+		c.bytes += file.size
+	}
+	for _, file := range files {
+		if c.bytes <= c.maxBytes {
+			return
+		}
+		c.remove(file.path, file.size)
+	}
+}
+
+// encodeDiskEntry lays an entry out as
+// checksum (32) | magic (4) | expiry in unix nanoseconds (8) | key length (4) | key | value,
+// where the checksum is the SHA-256 of everything after it
+func encodeDiskEntry(key string, expires time.Time, val []byte) []byte {
+	var body bytes.Buffer
+	body.Write(diskMagic)
+	binary.Write(&body, binary.BigEndian, expires.UnixNano())
+	binary.Write(&body, binary.BigEndian, uint32(len(key)))
+	body.WriteString(key)
+	body.Write(val)
+
+	sum := sha256.Sum256(body.Bytes())
+	return append(sum[:], body.Bytes()...)
+}
+
+var errCorruptDiskEntry = errors.New("corrupt disk cache entry")
+
+func decodeDiskEntry(data []byte) (string, time.Time, []byte, error) {
+	const headerSize = sha256.Size + 4 + 8 + 4
+	if len(data) < headerSize {
+		return "", time.Time{}, nil, errCorruptDiskEntry
+	}
+	sum, body := data[:sha256.Size], data[sha256.Size:]
+	if computed := sha256.Sum256(body); !bytes.Equal(sum, computed[:]) || !bytes.HasPrefix(body, diskMagic) {
+		return "", time.Time{}, nil, errCorruptDiskEntry
+	}
+
+	body = body[len(diskMagic):]
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(body[:8])))
+	keyLength := int(binary.BigEndian.Uint32(body[8:12]))
+	body = body[12:]
+	if keyLength > len(body) {
+		return "", time.Time{}, nil, errCorruptDiskEntry
+	}
+	return string(body[:keyLength]), expires, body[keyLength:], nil
+}
+
+// writeFileAtomic replaces path in one rename, so readers never see half a file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
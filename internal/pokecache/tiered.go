@@ -0,0 +1,116 @@
+package pokecache
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Layer is one level of a TieredCache, it has the same methods as internal.Cache
+type Layer interface {
+	Get(key string) ([]byte, bool)
+	Add(key string, val []byte)
+}
+
+// ttlLayer is a layer that can store an entry with its own TTL
+type ttlLayer interface {
+	AddWithTTL(key string, val []byte, ttl time.Duration)
+}
+
+// loadingLayer is a layer that can coalesce loads itself
+type loadingLayer interface {
+	GetOrLoad(key string, loader func() ([]byte, error)) ([]byte, error)
+}
+
+// TieredCache looks keys up from the fastest layer to the slowest, e.g. memory then disk.
+// A hit in a slower layer is copied into the faster ones, and Add writes to every layer.
+// Every layer applies its own TTL policy to what it is given.
+type TieredCache struct {
+	layers []Layer
+}
+
+// NewTieredCache composes layers, fastest first
+func NewTieredCache(layers ...Layer) *TieredCache {
+	return &TieredCache{layers: layers}
+}
+
+func (c *TieredCache) Get(key string) ([]byte, bool) {
+	for i, layer := range c.layers {
+		val, ok := layer.Get(key)
+		if !ok {
+			continue
+		}
+		c.addTo(c.layers[:i], key, val)
+		return val, true
+	}
+	return nil, false
+}
+
+// GetOrLoad looks key up in every layer and calls loader when all of them miss.
+// Loads are coalesced by the fastest layer when it supports it.
+func (c *TieredCache) GetOrLoad(key string, loader func() ([]byte, error)) ([]byte, error) {
+	if len(c.layers) == 0 {
+		return loader()
+	}
+	fastest, slower := c.layers[0], c.layers[1:]
+
+	// on a miss in the fastest layer, try the slower ones before the loader
+	loadSlower := func() ([]byte, error) {
+		slowerCache := TieredCache{layers: slower}
+		if val, ok := slowerCache.Get(key); ok {
+			return val, nil
+		}
+		val, err := loader()
+		if err != nil {
+			return nil, err
+		}
+		c.addTo(slower, key, val)
+		return val, nil
+	}
+
+	if loading, ok := fastest.(loadingLayer); ok {
+		return loading.GetOrLoad(key, loadSlower)
+	}
+	if val, ok := fastest.Get(key); ok {
+		return val, nil
+	}
+	val, err := loadSlower()
+	if err != nil {
+		return nil, err
+	}
+	c.addTo(c.layers[:1], key, val)
+	return val, nil
+}
+
+func (c *TieredCache) Add(key string, val []byte) {
+	c.addTo(c.layers, key, val)
+}
+
+// AddWithTTL writes the entry with its own TTL to every layer that supports one, and plainly to the others
+func (c *TieredCache) AddWithTTL(key string, val []byte, ttl time.Duration) {
+	for _, layer := range c.layers {
+		if withTTL, ok := layer.(ttlLayer); ok {
+			withTTL.AddWithTTL(key, val, ttl)
+		} else {
+			layer.Add(key, val)
+		}
+	}
+}
+
+// addTo writes the entry to layers
+func (c *TieredCache) addTo(layers []Layer, key string, val []byte) {
+	for _, layer := range layers {
+		layer.Add(key, val)
+	}
+}
+
+// Close closes every layer that can be closed
+func (c *TieredCache) Close() error {
+	var errs []error
+	for _, layer := range c.layers {
+		if closer, ok := layer.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
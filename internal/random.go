@@ -0,0 +1,10 @@
+package internal
+
+import "math/rand/v2"
+
+// SetSeed restarts the random numbers of the game from seed
+func (c *CliState) SetSeed(seed uint64) {
+	c.Seed = seed
+	c.RandSource = rand.NewPCG(seed, seed)
+	c.Rand = rand.New(c.RandSource)
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRequestsPerSecond = 10
+	DefaultBurst             = 20
+)
+
+// RateLimiter is a token bucket that can be shared by several services and goroutines
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // bucket capacity
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter allows requestsPerSecond on average with bursts of up to burst requests
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(max(1, burst)),
+		tokens: float64(max(1, burst)),
+		now:    time.Now,
+	}
+}
+
+// Wait blocks until a request may be sent and returns how long it waited.
+// The token is given back when ctx ends before the wait is over.
+func (l *RateLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	if l == nil || l.rate <= 0 {
+		return 0, ctx.Err()
+	}
+
+	delay := l.reserve()
+	if delay == 0 {
+		return 0, ctx.Err()
+	}
+
+	if err := sleepContext(ctx, delay); err != nil {
+		l.mu.Lock()
+		l.tokens = min(l.burst, l.tokens+1)
+		l.mu.Unlock()
+		return 0, err
+	}
+	return delay, nil
+}
+
+// reserve takes a token, possibly going into debt, and returns how long until that token exists
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Seconds()
+		l.tokens = min(l.burst, l.tokens+elapsed*l.rate)
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// WithRateLimit throttles the service to requestsPerSecond, a non-positive rate disables throttling
+func WithRateLimit(requestsPerSecond float64, burst int) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.rateLimiter = NewRateLimiter(requestsPerSecond, burst)
+	}
+}
+
+// WithRateLimiter shares an existing limiter, so several services stay under one budget
+func WithRateLimiter(limiter *RateLimiter) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.rateLimiter = limiter
+	}
+}
+
+// WithThrottleHook reports every request that had to wait for the rate limiter and for how long
+func WithThrottleHook(hook func(url string, waited time.Duration)) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.onThrottle = hook
+	}
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+// Get fetches and decodes a single resource.
+// path is relative to the base URL ("pokemon/pikachu") or an absolute URL taken from another resource.
+func Get[T any](ctx context.Context, s *PokeAPIService, path string) (T, error) {
+	var resource T
+	if err := s.fetch(ctx, s.resolve(path), &resource); err != nil {
+		var zero T
+		return zero, err
+	}
+	return resource, nil
+}
+
+// List fetches limit resources of a list endpoint such as "location-area", starting at offset
+func List[T any](ctx context.Context, s *PokeAPIService, path string, limit, offset int) (Page[T], error) {
+	url := fmt.Sprintf("%s?limit=%d&offset=%d", s.resolve(path), limit, offset)
+	var page Page[T]
+	if err := s.fetch(ctx, url, &page); err != nil {
+		return Page[T]{}, err
+	}
+	page.Limit = limit
+	page.Offset = offset
+	return page, nil
+}
+
+// All walks every resource of a list endpoint, fetching the next page only once the previous one is used up.
+// An error is yielded once and ends the iteration.
+func All[T any](ctx context.Context, s *PokeAPIService, path string, limit int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		page, err := List[T](ctx, s, path, limit, 0)
+		for {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Results {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !page.HasNext() {
+				return
+			}
+			next := s.resolve(page.Next)
+			page = Page[T]{}
+			err = s.fetch(ctx, next, &page)
+		}
+	}
+}
+
+// resolve turns a path or a resource URL into a URL on this service's base URL.
+// Resource URLs returned by the public API are rewritten so a mirror keeps serving the follow-up requests.
+func (s *PokeAPIService) resolve(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		if strings.HasPrefix(path, s.baseURL) {
+			return path
+		}
+		if _, rest, found := strings.Cut(path, "/api/v2/"); found {
+			return s.baseURL + "/" + rest
+		}
+		return path
+	}
+	return s.baseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// resourceName is the endpoint a URL belongs to, e.g. "pokemon" for .../pokemon/pikachu
+func (s *PokeAPIService) resourceName(url string) string {
+	path := strings.TrimPrefix(url, s.baseURL+"/")
+	path, _, _ = strings.Cut(path, "?")
+	name, _, _ := strings.Cut(path, "/")
+	return name
+}
+
+// ListCacheNamespace starts the cache key of every list page.
+// Lists grow when PokeAPI adds data, so caches should keep them for less long than single resources.
+const ListCacheNamespace = "list:"
+
+// CacheKey returns the key a response from url is cached under: its path under the base URL,
+// e.g. "pokemon/pikachu", with list pages in ListCacheNamespace
+func (s *PokeAPIService) CacheKey(url string) string {
+	key := strings.TrimPrefix(url, s.baseURL+"/")
+	if strings.Contains(key, "?") {
+		return ListCacheNamespace + key
+	}
+	return key
+}
+
+// FetchCacheKey requests the body cached under key again, bypassing the cache, e.g. to revalidate a stale entry
+func (s *PokeAPIService) FetchCacheKey(ctx context.Context, key string) ([]byte, error) {
+	return s.fetchBody(ctx, s.resolve(strings.TrimPrefix(key, ListCacheNamespace)))
+}
+
+// fetch is the one place that GETs a URL, maps its status to an error and decodes it into v.
+// Bodies go through the service's cache when it has one.
+func (s *PokeAPIService) fetch(ctx context.Context, url string, v any) error {
+	resource := s.resourceName(url)
+
+	body, err := s.cachedBody(ctx, url)
+	if err != nil {
+		s.metrics.errors.Add(1)
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		s.metrics.errors.Add(1)
+		return &DecodeError{Resource: resource, URL: url, Err: err}
+	}
+	return nil
+}
+
+// cachedBody returns the body of url from the cache, fetching it on a miss
+func (s *PokeAPIService) cachedBody(ctx context.Context, url string) ([]byte, error) {
+	if s.cache == nil {
+		return s.fetchBody(ctx, url)
+	}
+
+	fetched := false
+	body, err := s.cache.GetOrLoad(s.CacheKey(url), func() ([]byte, error) {
+		fetched = true
+		return s.fetchBody(ctx, url)
+	})
+	if err == nil && !fetched {
+		s.metrics.cacheHits.Add(1)
+	}
+	return body, err
+}
+
+func (s *PokeAPIService) fetchBody(ctx context.Context, url string) ([]byte, error) {
+	res, err := s.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", s.resourceName(url), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, newHTTPStatusError(res, url)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.resourceName(url), err)
+	}
+	return body, nil
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how PokeAPIService retries failed requests.
+// Only idempotent methods are retried, and only on transport errors or one of RetryableStatuses.
+type RetryPolicy struct {
+	MaxAttempts       int           // total attempts including the first one, 1 disables retries
+	BaseDelay         time.Duration // backoff before the first retry, doubled on every further retry
+	MaxDelay          time.Duration // upper bound for a single wait, Retry-After included
+	RetryableStatuses []int
+	OnRetry           func(RetryEvent) // optional hook called before every retry
+}
+
+// RetryEvent describes a failed attempt that is about to be retried
+type RetryEvent struct {
+	URL        string
+	Attempt    int // the attempt that just failed, starting at 1
+	StatusCode int // 0 when the attempt failed without a response
+	Err        error
+	Delay      time.Duration // how long the service waits before the next attempt
+}
+
+// RetryError is returned when every attempt of a request failed
+type RetryError struct {
+	URL      string
+	Attempts int
+	Err      error // the error of the last attempt
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up on %s after %d attempt(s): %v", e.URL, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRetryPolicy retries the statuses PokeAPI returns when it is overloaded or rate limiting
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   250 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		RetryableStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetryPolicy replaces the default retry policy
+func WithRetryPolicy(policy RetryPolicy) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.retryPolicy = policy
+	}
+}
+
+func (p RetryPolicy) attempts() int {
+	return max(1, p.MaxAttempts)
+}
+
+func (p RetryPolicy) retryableStatus(statusCode int) bool {
+	return slices.Contains(p.RetryableStatuses, statusCode)
+}
+
+// backoff returns the wait before the retry following the given failed attempt.
+// It uses exponential backoff with equal jitter, unless the server asked for a longer wait.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay>>(attempt-1) != p.BaseDelay {
+		// overflowed, a zero BaseDelay never does and keeps not backing off
+		delay = math.MaxInt64
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	delay = max(delay, retryAfter)
+	if p.MaxDelay > 0 {
+		delay = min(delay, p.MaxDelay)
+	}
+	return delay
+}
+
+func retryableMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(0, date.Sub(now))
+	}
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SaveVersion is the format SaveFile is written in, bump it together with a new entry in saveMigrations
+const SaveVersion = 1
+
+// saveMigration upgrades a decoded save file by one version in place
+type saveMigration func(save map[string]json.RawMessage) error
+
+// saveMigrations[i] turns a version i+1 save file into version i+2
+var saveMigrations []saveMigration
+
+// SaveFile is the game state kept between sessions
+type SaveFile struct {
+	Version         int                     `json:"version"`
+	SavedAt         time.Time               `json:"saved_at"`
+	Pokedex         map[string]PokedexEntry `json:"pokedex"`
+	Inventory       map[string]int          `json:"inventory"`
+	CurrentPage     int                     `json:"current_page"`
+	MapRegion       string                  `json:"map_region"`
+	CurrentLocation string                  `json:"current_location"`
+	CurrentArea     string                  `json:"current_area"`
+	WildEncounter   *WildPokemon            `json:"wild_encounter"`
+	Seed            uint64                  `json:"seed"`
+	RandState       []byte                  `json:"rand_state"`
+}
+
+// DefaultSavePath is save.json in the pokego directory of $XDG_DATA_HOME, ~/.local/share when unset
+func DefaultSavePath() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "pokego", "save.json"), nil
+}
+
+// Snapshot captures the parts of the state that are saved
+func (c *CliState) Snapshot() (SaveFile, error) {
+	randState, err := c.RandSource.MarshalBinary()
+	if err != nil {
+		return SaveFile{}, fmt.Errorf("failed to save the random number state: %w", err)
+	}
+	return SaveFile{
+		Version:         SaveVersion,
+		SavedAt:         time.Now(),
+		Pokedex:         c.Pokedex,
+		Inventory:       c.Inventory,
+		CurrentPage:     c.CurrentPage,
+		MapRegion:       c.MapRegion,
+		CurrentLocation: c.CurrentLocation,
+		CurrentArea:     c.CurrentArea,
+		WildEncounter:   c.WildEncounter,
+		Seed:            c.Seed,
+		RandState:       randState,
+	}, nil
+}
+
+// Restore replaces the saved parts of the state with save
+func (c *CliState) Restore(save SaveFile) error {
+	source := rand.NewPCG(save.Seed, save.Seed)
+	if len(save.RandState) > 0 {
+		if err := source.UnmarshalBinary(save.RandState); err != nil {
+			return fmt.Errorf("failed to restore the random number state: %w", err)
+		}
+	}
+
+	c.Pokedex = save.Pokedex
+	if c.Pokedex == nil {
+		c.Pokedex = make(map[string]PokedexEntry)
+	}
+	c.Inventory = save.Inventory
+	if c.Inventory == nil {
+		c.Inventory = make(map[string]int)
+	}
+	c.CurrentPage = save.CurrentPage
+	c.MapRegion = save.MapRegion
+	c.CurrentLocation = save.CurrentLocation
+	c.CurrentArea = save.CurrentArea
+	c.WildEncounter = save.WildEncounter
+	c.Seed = save.Seed
+	c.RandSource = source
+	c.Rand = rand.New(source)
+	return nil
+}
+
+// WriteSave writes save to path atomically: a crash leaves either the old or the new file, never half of one
+func WriteSave(path string, save SaveFile) error {
+	data, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode save file: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// the temporary file lives next to path, since a rename is only atomic within one file system
+	tmp, err := os.CreateTemp(dir, ".save-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadSave reads a save file, migrating it from older versions when needed
+func ReadSave(path string) (SaveFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SaveFile{}, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return SaveFile{}, fmt.Errorf("failed to decode save file: %w", err)
+	}
+	if err := migrateSave(raw, saveMigrations, SaveVersion); err != nil {
+		return SaveFile{}, err
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return SaveFile{}, err
+	}
+	var save SaveFile
+	if err := json.Unmarshal(migrated, &save); err != nil {
+		return SaveFile{}, fmt.Errorf("failed to decode save file: %w", err)
+	}
+	return save, nil
+}
+
+// migrateSave brings a decoded save file up to the target version
+func migrateSave(save map[string]json.RawMessage, migrations []saveMigration, target int) error {
+	var version int
+	if err := json.Unmarshal(save["version"], &version); err != nil || version < 1 {
+		return errors.New("not a pokego save file: missing version")
+	}
+	if version > target {
+		return fmt.Errorf("save file version %d is newer than this pokego supports (%d)", version, target)
+	}
+
+	for ; version < target; version++ {
+		if version > len(migrations) {
+			return fmt.Errorf("no migration from save file version %d", version)
+		}
+		if err := migrations[version-1](save); err != nil {
+			return fmt.Errorf("failed to migrate save file from version %d: %w", version, err)
+		}
+	}
+	versionData, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	save["version"] = versionData
+	return nil
+}
@@ -1,99 +1,316 @@
 package internal
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"io"
+	"iter"
 	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL   = "https://pokeapi.co/api/v2"
+	DefaultUserAgent = "pokego"
 )
 
 type PokeAPIService struct {
-	baseURL string
-	client  *http.Client
+	baseURL     string
+	client      *http.Client
+	userAgent   string
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+	onThrottle  func(url string, waited time.Duration)
+	cache       LoadingCache
+	metrics     serviceMetrics
 }
 
-func NewPokeAPIService() *PokeAPIService {
-	return &PokeAPIService{
-		baseURL: "https://pokeapi.co/api/v2",
-		client:  &http.Client{},
+// PokeAPIOption configures a PokeAPIService
+type PokeAPIOption func(*pokeAPIConfig)
+
+type pokeAPIConfig struct {
+	baseURL     string
+	client      *http.Client
+	timeout     time.Duration
+	userAgent   string
+	transport   http.RoundTripper
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+	onThrottle  func(url string, waited time.Duration)
+	cache       LoadingCache
+}
+
+// WithBaseURL points the service at a different PokeAPI host, e.g. a self-hosted mirror or an httptest.Server
+func WithBaseURL(baseURL string) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
 	}
 }
 
-func (s *PokeAPIService) GetLocationArea(locationArea string) (LocationArea, error) {
-	url := fmt.Sprintf("%s/location-area/%s", s.baseURL, locationArea)
-	res, err := s.client.Get(url)
-	if err != nil {
-		fmt.Println("error", err)
-		return LocationArea{}, fmt.Errorf("failed to get location area: %w", err)
+// WithHTTPClient uses the given client instead of a fresh one. The client is copied, never mutated.
+func WithHTTPClient(client *http.Client) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.client = client
 	}
-	defer res.Body.Close()
+}
 
-	if res.StatusCode == http.StatusNotFound {
-		return LocationArea{}, fmt.Errorf("location area not found: %s", locationArea)
+// WithTimeout sets the overall timeout of each HTTP request
+func WithTimeout(timeout time.Duration) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.timeout = timeout
 	}
+}
 
-	if res.StatusCode != http.StatusOK {
-		return LocationArea{}, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.userAgent = userAgent
 	}
+}
 
-	var decodedResponse LocationArea
-	if err := json.NewDecoder(res.Body).Decode(&decodedResponse); err != nil {
-		return LocationArea{}, fmt.Errorf("failed to decode location area: %w", err)
+// WithTransport sets the round tripper used by the HTTP client
+func WithTransport(transport http.RoundTripper) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.transport = transport
 	}
+}
 
-	return decodedResponse, nil
+// WithCache caches every response body under the key CacheKey gives its URL, so repeated lookups skip the network.
+// Concurrent lookups of the same URL share one request.
+func WithCache(cache LoadingCache) PokeAPIOption {
+	return func(c *pokeAPIConfig) {
+		c.cache = cache
+	}
 }
 
-func (s *PokeAPIService) GetLocationAreas(pageIndex int) ([]LocationArea, error) {
-	offset := pageIndex * 20
-	url := fmt.Sprintf("%s/location-area?offset=%d", s.baseURL, offset)
-	res, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get location areas: %w", err)
+func NewPokeAPIService(opts ...PokeAPIOption) *PokeAPIService {
+	config := pokeAPIConfig{
+		baseURL:     DefaultBaseURL,
+		userAgent:   DefaultUserAgent,
+		retryPolicy: DefaultRetryPolicy(),
+		rateLimiter: NewRateLimiter(DefaultRequestsPerSecond, DefaultBurst),
+	}
+	for _, opt := range opts {
+		opt(&config)
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	// copy the client so options never leak into a caller-owned client
+	client := &http.Client{}
+	if config.client != nil {
+		clientCopy := *config.client
+		client = &clientCopy
+	}
+	if config.timeout > 0 {
+		client.Timeout = config.timeout
+	}
+	if config.transport != nil {
+		client.Transport = config.transport
 	}
 
-	var decodedResponse LocationAreasAPIResponse
-	if err := json.NewDecoder(res.Body).Decode(&decodedResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode location areas: %w", err)
+	return &PokeAPIService{
+		baseURL:     config.baseURL,
+		client:      client,
+		userAgent:   config.userAgent,
+		retryPolicy: config.retryPolicy,
+		rateLimiter: config.rateLimiter,
+		onThrottle:  config.onThrottle,
+		cache:       config.cache,
 	}
+}
 
-	locationAreas := decodedResponse.Results
-	fmt.Println("Loaded", len(locationAreas), "location areas")
-	return locationAreas, nil
+// BaseURL returns the API root the service sends requests to
+func (s *PokeAPIService) BaseURL() string {
+	return s.baseURL
 }
 
-func (s *PokeAPIService) GetPokemon(pokemonName string) (Pokemon, error) {
-	url := fmt.Sprintf("%s/pokemon/%s", s.baseURL, pokemonName)
-	res, err := s.client.Get(url)
-	if err != nil {
-		return Pokemon{}, fmt.Errorf("failed to get pokemon: %w", err)
+// get sends a GET request, retrying transient failures according to the retry policy.
+// Any response it returns has a non-retryable status and must be closed by the caller.
+func (s *PokeAPIService) get(ctx context.Context, url string) (*http.Response, error) {
+	return s.do(ctx, http.MethodGet, url)
+}
+
+func (s *PokeAPIService) do(ctx context.Context, method, url string) (*http.Response, error) {
+	attempts := 1
+	if retryableMethod(method) {
+		attempts = s.retryPolicy.attempts()
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusNotFound {
-		return Pokemon{}, fmt.Errorf("pokemon not found: %s", pokemonName)
+	for attempt := 1; ; attempt++ {
+		// every attempt counts against the fair-use budget, retries included
+		waited, err := s.rateLimiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if waited > 0 {
+			s.metrics.throttled.Add(1)
+			s.metrics.throttleWait.Add(int64(waited))
+			if s.onThrottle != nil {
+				s.onThrottle(url, waited)
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if s.userAgent != "" {
+			req.Header.Set("User-Agent", s.userAgent)
+		}
+		req.Header.Set("Accept", "application/json")
+
+		var retryAfter time.Duration
+		statusCode := 0
+		s.metrics.requests.Add(1)
+		res, err := s.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				// cancelled by the caller, retrying would only delay the inevitable
+				return nil, err
+			}
+		} else if !s.retryPolicy.retryableStatus(res.StatusCode) {
+			return res, nil
+		} else {
+			statusCode = res.StatusCode
+			retryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+			err = newHTTPStatusError(res, url)
+			// drain so the connection can be reused
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		if attempt >= attempts {
+			return nil, &RetryError{URL: url, Attempts: attempt, Err: err}
+		}
+
+		s.metrics.retries.Add(1)
+		delay := s.retryPolicy.backoff(attempt, retryAfter)
+		if s.retryPolicy.OnRetry != nil {
+			s.retryPolicy.OnRetry(RetryEvent{
+				URL:        url,
+				Attempt:    attempt,
+				StatusCode: statusCode,
+				Err:        err,
+				Delay:      delay,
+			})
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
+}
+
+func (s *PokeAPIService) GetLocationArea(locationArea string) (LocationArea, error) {
+	return s.GetLocationAreaContext(context.Background(), locationArea)
+}
+
+// GetLocationAreaContext is GetLocationArea with a context that can cancel the request
+func (s *PokeAPIService) GetLocationAreaContext(ctx context.Context, locationArea string) (LocationArea, error) {
+	return Get[LocationArea](ctx, s, "location-area/"+locationArea)
+}
+
+func (s *PokeAPIService) GetLocationAreas(pageIndex int) ([]LocationArea, error) {
+	return s.GetLocationAreasContext(context.Background(), pageIndex)
+}
 
-	if res.StatusCode != http.StatusOK {
-		return Pokemon{}, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+// GetLocationAreasContext is GetLocationAreas with a context that can cancel the request
+func (s *PokeAPIService) GetLocationAreasContext(ctx context.Context, pageIndex int) ([]LocationArea, error) {
+	const pageLength = 20
+	page, err := List[LocationArea](ctx, s, "location-area", pageLength, pageIndex*pageLength)
+	if err != nil {
+		return nil, err
 	}
-	// // Read and print the raw response body
-	// bodyBytes, err := io.ReadAll(res.Body)
-	// if err != nil {
-	// 	return Pokemon{}, fmt.Errorf("failed to read response body: %w", err)
-	// }
 
-	// Print the raw JSON
-	// fmt.Printf("Raw API Response: %s\n", string(bodyBytes))
+	locationAreas := page.Results
+	fmt.Println("Loaded", len(locationAreas), "location areas")
+	return locationAreas, nil
+}
+
+// ListLocationAreasContext fetches limit location areas starting at offset, along with the total count
+func (s *PokeAPIService) ListLocationAreasContext(ctx context.Context, limit, offset int) (Page[NamedAPIResource], error) {
+	return List[NamedAPIResource](ctx, s, "location-area", limit, offset)
+}
+
+// AllLocationAreas lazily walks every location area, limit per request
+func (s *PokeAPIService) AllLocationAreas(ctx context.Context, limit int) iter.Seq2[NamedAPIResource, error] {
+	return All[NamedAPIResource](ctx, s, "location-area", limit)
+}
+
+func (s *PokeAPIService) GetPokemon(pokemonName string) (Pokemon, error) {
+	return s.GetPokemonContext(context.Background(), pokemonName)
+}
+
+// GetPokemonContext is GetPokemon with a context that can cancel the request
+func (s *PokeAPIService) GetPokemonContext(ctx context.Context, pokemonName string) (Pokemon, error) {
+	return Get[Pokemon](ctx, s, "pokemon/"+pokemonName)
+}
+
+// GetPokemonSpeciesContext fetches the species of a Pokemon, which holds its Pokedex entries and capture rate
+func (s *PokeAPIService) GetPokemonSpeciesContext(ctx context.Context, speciesName string) (PokemonSpecies, error) {
+	return Get[PokemonSpecies](ctx, s, "pokemon-species/"+speciesName)
+}
+
+// GetEvolutionChainContext fetches an evolution chain by id, see PokemonSpecies.EvolutionChain
+func (s *PokeAPIService) GetEvolutionChainContext(ctx context.Context, id int) (EvolutionChain, error) {
+	return Get[EvolutionChain](ctx, s, fmt.Sprintf("evolution-chain/%d", id))
+}
+
+// GetTypeContext fetches a type with its damage relations
+func (s *PokeAPIService) GetTypeContext(ctx context.Context, typeName string) (Type, error) {
+	return Get[Type](ctx, s, "type/"+typeName)
+}
+
+// GetMoveContext fetches a move with its power, accuracy and effect
+func (s *PokeAPIService) GetMoveContext(ctx context.Context, moveName string) (Move, error) {
+	return Get[Move](ctx, s, "move/"+moveName)
+}
+
+// GetAbilityContext fetches an ability with its effect and the Pokemon that can have it
+func (s *PokeAPIService) GetAbilityContext(ctx context.Context, abilityName string) (Ability, error) {
+	return Get[Ability](ctx, s, "ability/"+abilityName)
+}
 
-	var decodedResponse Pokemon
-	if err := json.NewDecoder(res.Body).Decode(&decodedResponse); err != nil {
-		return Pokemon{}, fmt.Errorf("failed to decode pokemon: %w", err)
+// GetItemContext fetches an item with its cost and effect
+func (s *PokeAPIService) GetItemContext(ctx context.Context, itemName string) (Item, error) {
+	return Get[Item](ctx, s, "item/"+itemName)
+}
+
+// GetItemCategoryContext fetches an item category with the items in it
+func (s *PokeAPIService) GetItemCategoryContext(ctx context.Context, categoryName string) (ItemCategory, error) {
+	return Get[ItemCategory](ctx, s, "item-category/"+categoryName)
+}
+
+// GetBerryContext fetches a berry by its name without the -berry suffix, e.g. "cheri"
+func (s *PokeAPIService) GetBerryContext(ctx context.Context, berryName string) (Berry, error) {
+	return Get[Berry](ctx, s, "berry/"+berryName)
+}
+
+// GetRegionContext fetches a region with its locations
+func (s *PokeAPIService) GetRegionContext(ctx context.Context, regionName string) (Region, error) {
+	return Get[Region](ctx, s, "region/"+regionName)
+}
+
+// GetLocationContext fetches a location with its areas
+func (s *PokeAPIService) GetLocationContext(ctx context.Context, locationName string) (Location, error) {
+	return Get[Location](ctx, s, "location/"+locationName)
+}
+
+// GetGenerationContext fetches a generation with what it introduced
+func (s *PokeAPIService) GetGenerationContext(ctx context.Context, generationName string) (Generation, error) {
+	return Get[Generation](ctx, s, "generation/"+generationName)
+}
+
+// GetResourceNamesContext lists the name of every resource of an endpoint such as "pokemon" or "location-area"
+func (s *PokeAPIService) GetResourceNamesContext(ctx context.Context, endpoint string) ([]string, error) {
+	// PokeAPI has no maximum page size, so a huge limit returns the whole list in one request
+	page, err := List[NamedAPIResource](ctx, s, endpoint, 100000, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	return decodedResponse, nil
+	names := make([]string, len(page.Results))
+	for i, resource := range page.Results {
+		names[i] = resource.Name
+	}
+	return names, nil
 }
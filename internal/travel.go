@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"embed"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// routes holds a map of walkable connections per region, PokeAPI has none.
+// Each file maps a location to some of the locations it connects to, connections go both ways.
+//
+//go:embed routes/*.json
+var routes embed.FS
+
+// routeMaps returns every region's connections by region and location name
+var routeMaps = sync.OnceValue(func() map[string]map[string][]string {
+	files, err := routes.ReadDir("routes")
+	if err != nil {
+		panic(err)
+	}
+
+	maps := make(map[string]map[string][]string)
+	for _, file := range files {
+		data, err := routes.ReadFile("routes/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		var connections map[string][]string
+		if err := json.Unmarshal(data, &connections); err != nil {
+			panic("malformed route map " + file.Name() + ": " + err.Error())
+		}
+
+		both := make(map[string][]string)
+		for from, tos := range connections {
+			for _, to := range tos {
+				both[from] = append(both[from], to)
+				both[to] = append(both[to], from)
+			}
+		}
+		maps[strings.TrimSuffix(file.Name(), ".json")] = both
+	}
+	return maps
+})
+
+// Neighbours returns the locations of the region that connect to location on its route map.
+// known is false when the map of the region does not have location, or there is no map at all.
+func (r Region) Neighbours(location string) (neighbours []string, known bool) {
+	connections, known := routeMaps()[r.Name][location]
+	if !known {
+		return nil, false
+	}
+
+	for _, l := range r.Locations {
+		if slices.Contains(connections, l.Name) {
+			neighbours = append(neighbours, l.Name)
+		}
+	}
+	slices.Sort(neighbours)
+	return neighbours, true
+}
+
+// Adjacent reports whether a player in location from can travel straight to location to in the region.
+// Locations missing from the route map connect to every location of the region, so they never strand the player.
+func (r Region) Adjacent(from, to string) bool {
+	if from == to {
+		return true
+	}
+	if !slices.ContainsFunc(r.Locations, func(l NamedAPIResource) bool { return l.Name == to }) {
+		return false
+	}
+
+	neighbours, known := r.Neighbours(from)
+	if !known {
+		return true
+	}
+	if _, toKnown := r.Neighbours(to); !toKnown {
+		return true
+	}
+	return slices.Contains(neighbours, to)
+}
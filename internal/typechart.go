@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+)
+
+// BattleTypes are the 18 types of the current games, in the order the games list them
+var BattleTypes = []string{
+	"normal", "fighting", "flying", "poison", "ground", "rock",
+	"bug", "ghost", "steel", "fire", "water", "grass",
+	"electric", "psychic", "ice", "dragon", "dark", "fairy",
+}
+
+// TypeChart is the matrix of damage multipliers between every pair of battle types
+type TypeChart struct {
+	multipliers [18][18]float64 // [attacking][defending]
+}
+
+// NewTypeChart builds the chart from the damage relations of the battle types.
+// Pairs no type mentions deal regular damage.
+func NewTypeChart(types []Type) (*TypeChart, error) {
+	chart := &TypeChart{}
+	for attacking := range chart.multipliers {
+		for defending := range chart.multipliers[attacking] {
+			chart.multipliers[attacking][defending] = 1
+		}
+	}
+
+	for _, t := range types {
+		attacking, ok := typeIndex(t.Name)
+		if !ok {
+			// unknown, shadow and stellar don't take part in the chart
+			continue
+		}
+		relations := []struct {
+			targets    []NamedAPIResource
+			multiplier float64
+		}{
+			{t.DamageRelations.NoDamageTo, 0},
+			{t.DamageRelations.HalfDamageTo, 0.5},
+			{t.DamageRelations.DoubleDamageTo, 2},
+		}
+		for _, relation := range relations {
+			for _, target := range relation.targets {
+				defending, ok := typeIndex(target.Name)
+				if !ok {
+					return nil, fmt.Errorf("type %s has a damage relation to unknown type %s", t.Name, target.Name)
+				}
+				chart.multipliers[attacking][defending] = relation.multiplier
+			}
+		}
+	}
+	return chart, nil
+}
+
+func typeIndex(name string) (int, bool) {
+	index := slices.Index(BattleTypes, name)
+	return index, index >= 0
+}
+
+// IsBattleType reports whether name is one of the 18 battle types
+func IsBattleType(name string) bool {
+	_, ok := typeIndex(name)
+	return ok
+}
+
+// Effectiveness is the damage multiplier of an attacking type against one or two defending types
+func (c *TypeChart) Effectiveness(attacking string, defending ...string) (float64, error) {
+	attackingIndex, ok := typeIndex(attacking)
+	if !ok {
+		return 0, fmt.Errorf("unknown type %s", attacking)
+	}
+
+	multiplier := 1.0
+	for _, d := range defending {
+		defendingIndex, ok := typeIndex(d)
+		if !ok {
+			return 0, fmt.Errorf("unknown type %s", d)
+		}
+		multiplier *= c.multipliers[attackingIndex][defendingIndex]
+	}
+	return multiplier, nil
+}
+
+// Weaknesses groups the attacking types by the multiplier they deal to the defending types.
+// Types dealing regular damage are left out.
+func (c *TypeChart) Weaknesses(defending ...string) (map[float64][]string, error) {
+	weaknesses := make(map[float64][]string)
+	for _, attacking := range BattleTypes {
+		multiplier, err := c.Effectiveness(attacking, defending...)
+		if err != nil {
+			return nil, err
+		}
+		if multiplier != 1 {
+			weaknesses[multiplier] = append(weaknesses[multiplier], attacking)
+		}
+	}
+	return weaknesses, nil
+}
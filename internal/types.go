@@ -1,23 +1,57 @@
 package internal
 
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
 // Cache defines the interface for caching operations
 type Cache interface {
 	Get(key string) ([]byte, bool)
 	Add(key string, val []byte)
 }
 
+// LoadingCache is a Cache that fills misses itself, sharing one load between concurrent misses of a key
+type LoadingCache interface {
+	Cache
+	GetOrLoad(key string, loader func() ([]byte, error)) ([]byte, error)
+}
+
 // main types
 
 type CliState struct {
 	CurrentCommand CliCommand
 	CurrentPage    int
+	MapRegion      string // region map pages through, empty for every location area
 	CommandHistory []CliEvent
 	// LoadedData        DataLoad
-	Cache             Cache
+	Cache             LoadingCache
+	APIService        *PokeAPIService
 	PageLength        int
 	AvailableCommands map[string]CliCommand
 
-	Pokedex map[string]Pokemon
+	Pokedex map[string]PokedexEntry
+
+	CurrentLocation string       // where the player is, empty until the first goto
+	CurrentArea     string       // area of CurrentLocation that explore, walk, surf and fish default to
+	WildEncounter   *WildPokemon // the wild Pokemon catch targets, nil when there is none
+
+	Inventory map[string]int // balls left in the bag by item name
+
+	Seed       uint64     // seed Rand started from, replaying a session with it gives the same encounters and catches
+	Rand       *rand.Rand // every random game decision draws from it, see SetSeed
+	RandSource *rand.PCG  // the state behind Rand, saved so a loaded game carries on with the same numbers
+	SavePath   string     // save file used by save, load and autosave, empty disables autosave
+
+	TypeChart *TypeChart // built on first use, it takes a request per type
+}
+
+// PokedexEntry is a caught Pokemon along with what the game tracks about it
+type PokedexEntry struct {
+	Pokemon Pokemon `json:"pokemon"`
+	Level   int     `json:"level"`
 }
 
 type CliEvent struct {
@@ -30,7 +64,7 @@ type CliEvent struct {
 type CliCommand struct {
 	Name        string
 	Description string
-	Callback    func(*CliState, []string) (string, error) // accepts current state and command arguments
+	Callback    func(context.Context, *CliState, []string) (string, error) // accepts a per-command context, current state and command arguments
 }
 
 // data types
@@ -55,10 +89,36 @@ type VersionDetail struct {
 	Version Version `json:"version"`
 }
 
+// Location is both the /location resource and the reference to it inside a LocationArea, which only sets Name and URL
 type Location struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	ID     int                `json:"id"`
+	Name   string             `json:"name"`
+	URL    string             `json:"url"`
+	Region *NamedAPIResource  `json:"region"`
+	Names  []Name             `json:"names"`
+	Areas  []NamedAPIResource `json:"areas"`
+}
+
+type Region struct {
+	ID             int                `json:"id"`
+	Name           string             `json:"name"`
+	Locations      []NamedAPIResource `json:"locations"` // in id order, see Neighbours for how they connect
+	MainGeneration *NamedAPIResource  `json:"main_generation"`
+	Pokedexes      []NamedAPIResource `json:"pokedexes"`
+	VersionGroups  []NamedAPIResource `json:"version_groups"`
+	Names          []Name             `json:"names"`
+}
+
+type Generation struct {
+	ID             int                `json:"id"`
+	Name           string             `json:"name"`
+	MainRegion     NamedAPIResource   `json:"main_region"`
+	PokemonSpecies []NamedAPIResource `json:"pokemon_species"`
+	Moves          []NamedAPIResource `json:"moves"`
+	Abilities      []NamedAPIResource `json:"abilities"`
+	Types          []NamedAPIResource `json:"types"`
+	VersionGroups  []NamedAPIResource `json:"version_groups"`
+	Names          []Name             `json:"names"`
 }
 
 type Name struct {
@@ -88,6 +148,8 @@ type Pokemon struct {
 	Weight         int    `json:"weight"`
 	IsDefault      bool   `json:"is_default"`
 
+	Species NamedAPIResource `json:"species"`
+
 	// Stats array
 	Stats []struct {
 		BaseStat int `json:"base_stat"`
@@ -117,6 +179,9 @@ type Pokemon struct {
 		Slot     int  `json:"slot"`
 	} `json:"abilities"`
 
+	// Moves array, with how and when each move is learned per version group
+	Moves []PokemonMove `json:"moves"`
+
 	// Sprites (for images)
 	// Sprites struct {
 	// 	FrontDefault string `json:"front_default"`
@@ -126,6 +191,280 @@ type Pokemon struct {
 	// } `json:"sprites"`
 }
 
+type PokemonMove struct {
+	Move                NamedAPIResource     `json:"move"`
+	VersionGroupDetails []PokemonMoveVersion `json:"version_group_details"`
+}
+
+type PokemonMoveVersion struct {
+	MoveLearnMethod NamedAPIResource `json:"move_learn_method"`
+	VersionGroup    NamedAPIResource `json:"version_group"`
+	LevelLearnedAt  int              `json:"level_learned_at"`
+}
+
+type Move struct {
+	ID            int              `json:"id"`
+	Name          string           `json:"name"`
+	Accuracy      *int             `json:"accuracy"` // nil for moves that never miss
+	EffectChance  *int             `json:"effect_chance"`
+	PP            *int             `json:"pp"`
+	Priority      int              `json:"priority"`
+	Power         *int             `json:"power"` // nil for status moves
+	DamageClass   NamedAPIResource `json:"damage_class"`
+	Type          NamedAPIResource `json:"type"`
+	Target        NamedAPIResource `json:"target"`
+	Generation    NamedAPIResource `json:"generation"`
+	EffectEntries []VerboseEffect  `json:"effect_entries"`
+}
+
+// VerboseEffect is the effect text of a move, ability or item in one language
+type VerboseEffect struct {
+	Effect      string           `json:"effect"`
+	ShortEffect string           `json:"short_effect"`
+	Language    NamedAPIResource `json:"language"`
+}
+
+func effectIn(entries []VerboseEffect, language string) (VerboseEffect, bool) {
+	for _, entry := range entries {
+		if entry.Language.Name == language {
+			return entry, true
+		}
+	}
+	return VerboseEffect{}, false
+}
+
+// ShortEffect returns the short effect text in the given language with the effect chance filled in
+func (m Move) ShortEffect(language string) string {
+	entry, _ := effectIn(m.EffectEntries, language)
+	if m.EffectChance != nil {
+		return strings.ReplaceAll(entry.ShortEffect, "$effect_chance", fmt.Sprint(*m.EffectChance))
+	}
+	return entry.ShortEffect
+}
+
+type Ability struct {
+	ID                int                 `json:"id"`
+	Name              string              `json:"name"`
+	IsMainSeries      bool                `json:"is_main_series"`
+	Generation        NamedAPIResource    `json:"generation"`
+	Names             []Name              `json:"names"`
+	EffectEntries     []VerboseEffect     `json:"effect_entries"`
+	FlavorTextEntries []AbilityFlavorText `json:"flavor_text_entries"`
+	Pokemon           []AbilityPokemon    `json:"pokemon"`
+}
+
+type AbilityFlavorText struct {
+	FlavorText   string           `json:"flavor_text"`
+	Language     NamedAPIResource `json:"language"`
+	VersionGroup NamedAPIResource `json:"version_group"`
+}
+
+// AbilityPokemon is a Pokemon that can have an ability
+type AbilityPokemon struct {
+	IsHidden bool             `json:"is_hidden"`
+	Slot     int              `json:"slot"`
+	Pokemon  NamedAPIResource `json:"pokemon"`
+}
+
+// Effect returns the full effect text in the given language
+func (a Ability) Effect(language string) string {
+	entry, _ := effectIn(a.EffectEntries, language)
+	return entry.Effect
+}
+
+// ShortEffect returns the short effect text in the given language.
+// Newer abilities have no effect entries yet, their most recent flavor text is used instead.
+func (a Ability) ShortEffect(language string) string {
+	if entry, ok := effectIn(a.EffectEntries, language); ok {
+		return entry.ShortEffect
+	}
+	for i := len(a.FlavorTextEntries) - 1; i >= 0; i-- {
+		if entry := a.FlavorTextEntries[i]; entry.Language.Name == language {
+			return cleanFlavorText(entry.FlavorText)
+		}
+	}
+	return ""
+}
+
+type Item struct {
+	ID                int                      `json:"id"`
+	Name              string                   `json:"name"`
+	Cost              int                      `json:"cost"`
+	FlingPower        *int                     `json:"fling_power"`
+	FlingEffect       *NamedAPIResource        `json:"fling_effect"`
+	Attributes        []NamedAPIResource       `json:"attributes"` // e.g. countable, consumable, usable-in-battle
+	Category          NamedAPIResource         `json:"category"`
+	EffectEntries     []VerboseEffect          `json:"effect_entries"`
+	FlavorTextEntries []VersionGroupFlavorText `json:"flavor_text_entries"`
+	Names             []Name                   `json:"names"`
+}
+
+type VersionGroupFlavorText struct {
+	Text         string           `json:"text"`
+	Language     NamedAPIResource `json:"language"`
+	VersionGroup NamedAPIResource `json:"version_group"`
+}
+
+// ShortEffect returns the short effect text in the given language, or the latest flavor text when there is none
+func (i Item) ShortEffect(language string) string {
+	if entry, ok := effectIn(i.EffectEntries, language); ok {
+		return entry.ShortEffect
+	}
+	for j := len(i.FlavorTextEntries) - 1; j >= 0; j-- {
+		if entry := i.FlavorTextEntries[j]; entry.Language.Name == language {
+			return cleanFlavorText(entry.Text)
+		}
+	}
+	return ""
+}
+
+type ItemCategory struct {
+	ID     int                `json:"id"`
+	Name   string             `json:"name"`
+	Items  []NamedAPIResource `json:"items"`
+	Pocket NamedAPIResource   `json:"pocket"`
+	Names  []Name             `json:"names"`
+}
+
+type Berry struct {
+	ID               int              `json:"id"`
+	Name             string           `json:"name"`
+	GrowthTime       int              `json:"growth_time"` // hours per growth stage
+	MaxHarvest       int              `json:"max_harvest"`
+	NaturalGiftPower int              `json:"natural_gift_power"`
+	Size             int              `json:"size"` // millimeters
+	Smoothness       int              `json:"smoothness"`
+	SoilDryness      int              `json:"soil_dryness"`
+	Firmness         NamedAPIResource `json:"firmness"`
+	Flavors          []BerryFlavorMap `json:"flavors"`
+	Item             NamedAPIResource `json:"item"`
+	NaturalGiftType  NamedAPIResource `json:"natural_gift_type"`
+}
+
+type BerryFlavorMap struct {
+	Potency int              `json:"potency"`
+	Flavor  NamedAPIResource `json:"flavor"`
+}
+
+type PokemonSpecies struct {
+	ID                   int                `json:"id"`
+	Name                 string             `json:"name"`
+	Order                int                `json:"order"`
+	GenderRate           int                `json:"gender_rate"` // chance of being female in eighths, -1 for genderless
+	CaptureRate          int                `json:"capture_rate"`
+	BaseHappiness        int                `json:"base_happiness"`
+	IsBaby               bool               `json:"is_baby"`
+	IsLegendary          bool               `json:"is_legendary"`
+	IsMythical           bool               `json:"is_mythical"`
+	HatchCounter         int                `json:"hatch_counter"`
+	HasGenderDifferences bool               `json:"has_gender_differences"`
+	GrowthRate           NamedAPIResource   `json:"growth_rate"`
+	EggGroups            []NamedAPIResource `json:"egg_groups"`
+	Color                NamedAPIResource   `json:"color"`
+	Shape                NamedAPIResource   `json:"shape"`
+	EvolvesFromSpecies   *NamedAPIResource  `json:"evolves_from_species"`
+	EvolutionChain       APIResource        `json:"evolution_chain"`
+	Habitat              *NamedAPIResource  `json:"habitat"`
+	Generation           NamedAPIResource   `json:"generation"`
+	Names                []Name             `json:"names"`
+	FlavorTextEntries    []FlavorText       `json:"flavor_text_entries"`
+	Genera               []Genus            `json:"genera"`
+}
+
+type FlavorText struct {
+	FlavorText string           `json:"flavor_text"`
+	Language   NamedAPIResource `json:"language"`
+	Version    NamedAPIResource `json:"version"`
+}
+
+type Genus struct {
+	Genus    string           `json:"genus"`
+	Language NamedAPIResource `json:"language"`
+}
+
+// FlavorText returns the most recent Pokedex entry in the given language, with the game's line breaks removed
+func (s PokemonSpecies) FlavorText(language string) string {
+	for i := len(s.FlavorTextEntries) - 1; i >= 0; i-- {
+		entry := s.FlavorTextEntries[i]
+		if entry.Language.Name == language {
+			return cleanFlavorText(entry.FlavorText)
+		}
+	}
+	return ""
+}
+
+// Genus returns the species' category in the given language, e.g. "Seed Pokémon"
+func (s PokemonSpecies) Genus(language string) string {
+	for _, genus := range s.Genera {
+		if genus.Language.Name == language {
+			return genus.Genus
+		}
+	}
+	return ""
+}
+
+// cleanFlavorText strips the form feeds, soft hyphens and hard wraps the games use for their text boxes
+func cleanFlavorText(text string) string {
+	text = strings.NewReplacer("\u00ad\n", "", "\u00ad", "", "-\n", "-").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+type EvolutionChain struct {
+	ID              int               `json:"id"`
+	BabyTriggerItem *NamedAPIResource `json:"baby_trigger_item"`
+	Chain           ChainLink         `json:"chain"`
+}
+
+// ChainLink is one species of an evolution chain and the species it can evolve into
+type ChainLink struct {
+	IsBaby           bool              `json:"is_baby"`
+	Species          NamedAPIResource  `json:"species"`
+	EvolutionDetails []EvolutionDetail `json:"evolution_details"` // how the previous link evolves into this one
+	EvolvesTo        []ChainLink       `json:"evolves_to"`
+}
+
+// EvolutionDetail is one set of conditions for an evolution, nil and zero fields are not required
+type EvolutionDetail struct {
+	Trigger               NamedAPIResource  `json:"trigger"`
+	Item                  *NamedAPIResource `json:"item"`
+	Gender                *int              `json:"gender"`
+	HeldItem              *NamedAPIResource `json:"held_item"`
+	KnownMove             *NamedAPIResource `json:"known_move"`
+	KnownMoveType         *NamedAPIResource `json:"known_move_type"`
+	Location              *NamedAPIResource `json:"location"`
+	MinLevel              *int              `json:"min_level"`
+	MinHappiness          *int              `json:"min_happiness"`
+	MinBeauty             *int              `json:"min_beauty"`
+	MinAffection          *int              `json:"min_affection"`
+	NeedsOverworldRain    bool              `json:"needs_overworld_rain"`
+	PartySpecies          *NamedAPIResource `json:"party_species"`
+	PartyType             *NamedAPIResource `json:"party_type"`
+	RelativePhysicalStats *int              `json:"relative_physical_stats"`
+	TimeOfDay             string            `json:"time_of_day"`
+	TradeSpecies          *NamedAPIResource `json:"trade_species"`
+	TurnUpsideDown        bool              `json:"turn_upside_down"`
+}
+
+type Type struct {
+	ID              int                `json:"id"`
+	Name            string             `json:"name"`
+	DamageRelations TypeRelations      `json:"damage_relations"`
+	Generation      NamedAPIResource   `json:"generation"`
+	MoveDamageClass *NamedAPIResource  `json:"move_damage_class"`
+	Names           []Name             `json:"names"`
+	Moves           []NamedAPIResource `json:"moves"`
+}
+
+// TypeRelations lists the types a type deals or takes altered damage from
+type TypeRelations struct {
+	NoDamageTo       []NamedAPIResource `json:"no_damage_to"`
+	HalfDamageTo     []NamedAPIResource `json:"half_damage_to"`
+	DoubleDamageTo   []NamedAPIResource `json:"double_damage_to"`
+	NoDamageFrom     []NamedAPIResource `json:"no_damage_from"`
+	HalfDamageFrom   []NamedAPIResource `json:"half_damage_from"`
+	DoubleDamageFrom []NamedAPIResource `json:"double_damage_from"`
+}
+
 type VersionEncounterDetail struct {
 	Version          Version     `json:"version"`
 	MaxChance        int         `json:"max_chance"`
@@ -168,9 +507,15 @@ type EncounterCondition struct {
 
 // api service types
 
-type LocationAreasAPIResponse struct {
-	Count    int            `json:"count"`
-	Next     string         `json:"next"`
-	Previous string         `json:"previous"`
-	Results  []LocationArea `json:"results"`
+type LocationAreasAPIResponse = Page[LocationArea]
+
+type NamedAPIResource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type NamedAPIResourceList = Page[NamedAPIResource]
+
+type APIResource struct {
+	URL string `json:"url"`
 }
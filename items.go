@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandItem(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	positional, flags := parseFlags(commandArgs)
+	if categoryName, ok := flags["category"]; ok {
+		return itemCategoryOutput(ctx, cliState, categoryName)
+	}
+	if len(positional) == 0 {
+		return "Please provide the name of an item, or --category <name>", nil
+	}
+	itemName := positional[0]
+
+	item, err := cliState.APIService.GetItemContext(ctx, itemName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "item", itemName, err)
+	}
+
+	output := fmt.Sprintf("Name: %s\n", item.Name)
+	output += fmt.Sprintf("Category: %s\n", item.Category.Name)
+	output += fmt.Sprintf("Cost: %d\n", item.Cost)
+	output += fmt.Sprintf("Fling power: %s\n", optionalStat(item.FlingPower))
+	if len(item.Attributes) > 0 {
+		attributes := make([]string, len(item.Attributes))
+		for i, attribute := range item.Attributes {
+			attributes[i] = attribute.Name
+		}
+		output += fmt.Sprintf("Attributes: %s\n", strings.Join(attributes, ", "))
+	}
+	if effect := item.ShortEffect("en"); effect != "" {
+		output += effect + "\n"
+	}
+	return output, nil
+}
+
+func itemCategoryOutput(ctx context.Context, cliState *internal.CliState, categoryName string) (string, error) {
+	if categoryName == "" {
+		return "Please provide the name of an item category", nil
+	}
+	category, err := cliState.APIService.GetItemCategoryContext(ctx, categoryName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "item-category", categoryName, err)
+	}
+
+	output := fmt.Sprintf("%s (%s pocket):\n", category.Name, category.Pocket.Name)
+	for _, item := range category.Items {
+		output += fmt.Sprintf(" - %s\n", item.Name)
+	}
+	return output, nil
+}
+
+func commandBerry(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of a berry", nil
+	}
+	// berries are named without the suffix their items have
+	berryName := strings.TrimSuffix(commandArgs[0], "-berry")
+
+	berry, err := cliState.APIService.GetBerryContext(ctx, berryName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "berry", berryName, err)
+	}
+
+	output := fmt.Sprintf("Name: %s\n", berry.Item.Name)
+	output += fmt.Sprintf("Firmness: %s\n", berry.Firmness.Name)
+	output += fmt.Sprintf("Size: %dmm\n", berry.Size)
+	output += fmt.Sprintf("Growth time: %d hours per stage\n", berry.GrowthTime)
+	output += fmt.Sprintf("Max harvest: %d\n", berry.MaxHarvest)
+	output += fmt.Sprintf("Natural gift: %s, power %d\n", berry.NaturalGiftType.Name, berry.NaturalGiftPower)
+	output += "Flavors:\n"
+	for _, flavor := range berry.Flavors {
+		if flavor.Potency > 0 {
+			output += fmt.Sprintf("  - %s: %d\n", flavor.Flavor.Name, flavor.Potency)
+		}
+	}
+
+	// the effect lives on the berry's item
+	item, err := cliState.APIService.GetItemContext(ctx, berry.Item.Name)
+	if err != nil {
+		return output, fmt.Errorf("could not load the berry's effect: %w", err)
+	}
+	if effect := item.ShortEffect("en"); effect != "" {
+		output += effect + "\n"
+	}
+	return output, nil
+}
+
+func commandBag(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	output := "Bag:\n"
+	for _, ball := range internal.Balls {
+		if count := cliState.Inventory[ball]; count > 0 {
+			output += fmt.Sprintf(" - %s x%d\n", ball, count)
+		}
+	}
+	if output == "Bag:\n" {
+		return "Your bag is empty", nil
+	}
+	return output, nil
+}
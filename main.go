@@ -2,11 +2,19 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
+	"math/rand/v2"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/weirdwyrd/pokego/internal"
@@ -14,12 +22,52 @@ import (
 )
 
 func main() {
-	cliState := initCli()
+	apiURL := flag.String("api-url", internal.DefaultBaseURL, "PokeAPI base URL, e.g. a self-hosted mirror")
+	apiTimeout := flag.Duration("api-timeout", 30*time.Second, "timeout for each PokeAPI request")
+	apiRetries := flag.Int("api-retries", internal.DefaultRetryPolicy().MaxAttempts, "attempts per PokeAPI request before giving up")
+	apiRate := flag.Float64("api-rps", internal.DefaultRequestsPerSecond, "maximum PokeAPI requests per second, 0 disables throttling")
+	apiBurst := flag.Int("api-burst", internal.DefaultBurst, "PokeAPI requests allowed in a burst before throttling")
+	defaultSavePath, _ := internal.DefaultSavePath()
+	saveFile := flag.String("save-file", defaultSavePath, "save file loaded on start and written on exit, empty disables autosave")
+	defaultCacheDir, _ := pokecache.DefaultDiskCacheDir()
+	cacheDir := flag.String("cache-dir", defaultCacheDir, "directory PokeAPI data is cached in between runs, empty keeps the cache in memory only")
+	cacheTTL := flag.Duration("cache-ttl", pokecache.DefaultDiskTTL, "the longest PokeAPI data cached on disk stays valid")
+	seed := flag.Uint64("seed", 0, "seed for encounters and catches, to replay a session exactly; 0 picks a random one")
+	flag.Parse()
+
+	retryPolicy := internal.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = *apiRetries
+	retryPolicy.OnRetry = func(event internal.RetryEvent) {
+		fmt.Printf("PokeAPI request failed (%v), retrying in %s...\n", event.Err, event.Delay.Round(time.Millisecond))
+	}
+
+	cliState := initCli(
+		*cacheDir,
+		*cacheTTL,
+		internal.WithBaseURL(*apiURL),
+		internal.WithTimeout(*apiTimeout),
+		internal.WithRetryPolicy(retryPolicy),
+		internal.WithRateLimit(*apiRate, *apiBurst),
+		internal.WithThrottleHook(func(url string, waited time.Duration) {
+			if waited >= time.Second {
+				fmt.Printf("Waited %s to stay within the PokeAPI rate limit\n", waited.Round(time.Millisecond))
+			}
+		}),
+	)
+	cliState.SavePath = *saveFile
+	loadOnStart(cliState)
+	if *seed != 0 {
+		cliState.SetSeed(*seed)
+	}
 	startScanner(cliState)
 }
 
-func initCli() *internal.CliState {
-	cache, err := pokecache.NewCache(5 * time.Second)
+func initCli(diskCacheDir string, diskCacheTTL time.Duration, apiOptions ...internal.PokeAPIOption) *internal.CliState {
+	// the cache refreshes stale entries through the service, which looks everything up in the cache
+	var apiService *internal.PokeAPIService
+	cache, err := newCache(func(ctx context.Context, key string) ([]byte, error) {
+		return apiService.FetchCacheKey(ctx, key)
+	}, diskCacheDir, diskCacheTTL)
 	if err != nil {
 		fmt.Println("Error creating cache:", err)
 		os.Exit(1)
@@ -27,14 +75,17 @@ func initCli() *internal.CliState {
 		// cache = nil
 		// todo prompt user to continue without cache
 	}
+	apiService = internal.NewPokeAPIService(append(apiOptions, internal.WithCache(cache))...)
 
-	return &internal.CliState{
+	cliState := &internal.CliState{
 		CurrentCommand: internal.CliCommand{},
 		CurrentPage:    0,
 		Cache:          cache,
+		APIService:     apiService,
 		PageLength:     20,
 		CommandHistory: []internal.CliEvent{},
-		Pokedex:        make(map[string]internal.Pokemon),
+		Pokedex:        make(map[string]internal.PokedexEntry),
+		Inventory:      internal.StartingInventory(),
 		AvailableCommands: map[string]internal.CliCommand{
 			"help": {
 				Name:        "help",
@@ -43,12 +94,12 @@ func initCli() *internal.CliState {
 			},
 			"exit": {
 				Name:        "exit",
-				Description: "Exits the program",
+				Description: "Saves the game and exits the program",
 				Callback:    commandExit,
 			},
 			"map": {
 				Name:        "map",
-				Description: "Shows the map of location-areas one page at a time. map again to go forward. mapb to go backward.",
+				Description: "Shows the map of location-areas one page at a time. map again to go forward. mapb to go backward. map <region> to stay in one region, map all to go back to every region.",
 				Callback:    commandMap,
 			},
 			"mapb": {
@@ -58,14 +109,64 @@ func initCli() *internal.CliState {
 			},
 			"explore": {
 				Name:        "explore",
-				Description: "Explore the map",
+				Description: "Shows the wild Pokemon of a location area: explore [area] [--version <game>], the current area by default",
 				Callback:    commandExplore,
 			},
 			"catch": {
 				Name:        "catch",
-				Description: "Attempts to catch a Pokemon",
+				Description: "Throws a ball at the wild Pokemon in front of you: catch [pokemon] [ball] [--hp <percent>] [--status <condition>]",
 				Callback:    commandCatch,
 			},
+			"goto": {
+				Name:        "goto",
+				Description: "Travels to an area in the current or a neighbouring location: goto [area]",
+				Callback:    commandGoto,
+			},
+			"walk": {
+				Name:        "walk",
+				Description: "Walks through the tall grass of the current area: walk [--version <game>]",
+				Callback:    commandWalk,
+			},
+			"surf": {
+				Name:        "surf",
+				Description: "Surfs the water of the current area: surf [--version <game>]",
+				Callback:    commandSurf,
+			},
+			"fish": {
+				Name:        "fish",
+				Description: "Fishes in the current area: fish <old-rod|good-rod|super-rod> [--version <game>]",
+				Callback:    commandFish,
+			},
+			"flee": {
+				Name:        "flee",
+				Description: "Runs away from the wild Pokemon in front of you",
+				Callback:    commandFlee,
+			},
+			"seed": {
+				Name:        "seed",
+				Description: "Shows or sets the seed of encounters and catches: seed [n]",
+				Callback:    commandSeed,
+			},
+			"stats": {
+				Name:        "stats",
+				Description: "Shows how many PokeAPI requests this session made",
+				Callback:    commandStats,
+			},
+			"bag": {
+				Name:        "bag",
+				Description: "Shows the balls left in your bag",
+				Callback:    commandBag,
+			},
+			"save": {
+				Name:        "save",
+				Description: "Saves your Pokedex and progress: save [file]",
+				Callback:    commandSave,
+			},
+			"load": {
+				Name:        "load",
+				Description: "Loads a saved game: load [file]",
+				Callback:    commandLoad,
+			},
 			"inspect": {
 				Name:        "inspect",
 				Description: "Inspect a Pokemon in your Pokedex",
@@ -76,6 +177,61 @@ func initCli() *internal.CliState {
 				Description: "Gotta catch em all!",
 				Callback:    commandPokedex,
 			},
+			"evolution": {
+				Name:        "evolution",
+				Description: "Shows the evolution tree of a Pokemon: evolution <pokemon>",
+				Callback:    commandEvolution,
+			},
+			"weakness": {
+				Name:        "weakness",
+				Description: "Shows the type matchups of a Pokemon or a type pair: weakness <pokemon> | weakness <type> [type]",
+				Callback:    commandWeakness,
+			},
+			"moves": {
+				Name:        "moves",
+				Description: "Shows the moves a Pokemon learns: moves <pokemon> [--method level-up|machine|egg|tutor] [--version red-blue]",
+				Callback:    commandMoves,
+			},
+			"move": {
+				Name:        "move",
+				Description: "Shows the details of a move: move <name>",
+				Callback:    commandMove,
+			},
+			"ability": {
+				Name:        "ability",
+				Description: "Shows what an ability does and who can have it: ability <name>",
+				Callback:    commandAbility,
+			},
+			"item": {
+				Name:        "item",
+				Description: "Shows the details of an item or lists a category: item <name> | item --category <category>",
+				Callback:    commandItem,
+			},
+			"berry": {
+				Name:        "berry",
+				Description: "Shows the flavors, growth and effect of a berry: berry <name>",
+				Callback:    commandBerry,
+			},
+			"regions": {
+				Name:        "regions",
+				Description: "Lists every region",
+				Callback:    commandRegions,
+			},
+			"region": {
+				Name:        "region",
+				Description: "Lists the locations of a region: region <name>",
+				Callback:    commandRegion,
+			},
+			"location": {
+				Name:        "location",
+				Description: "Lists the areas of a location: location <name>",
+				Callback:    commandLocation,
+			},
+			"evolve": {
+				Name:        "evolve",
+				Description: "Evolves a Pokemon in your Pokedex if it meets the conditions: evolve <pokemon> [item]",
+				Callback:    commandEvolve,
+			},
 			// "undo": {
 			// 	Name:        "undo",
 			// 	Description: "Undoes the last command",
@@ -83,14 +239,64 @@ func initCli() *internal.CliState {
 			// },
 		},
 	}
+	cliState.SetSeed(rand.Uint64())
+	return cliState
+}
+
+// interruptHandler turns Ctrl-C into cancellation of the command that is currently running
+type interruptHandler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newInterruptHandler() *interruptHandler {
+	handler := &interruptHandler{}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		for range signals {
+			handler.interrupt()
+		}
+	}()
+	return handler
+}
+
+func (h *interruptHandler) interrupt() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cancel == nil {
+		// nothing in flight, keep the REPL alive
+		fmt.Print("\n(use exit to quit)\nPokedex >")
+		return
+	}
+	h.cancel()
+}
+
+// commandContext returns a context that is cancelled by Ctrl-C until release is called
+func (h *interruptHandler) commandContext() (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.mu.Lock()
+	h.cancel = cancel
+	h.mu.Unlock()
+	return ctx, func() {
+		h.mu.Lock()
+		h.cancel = nil
+		h.mu.Unlock()
+		cancel()
+	}
 }
 
 func startScanner(cliState *internal.CliState) {
 	scanner := bufio.NewScanner(os.Stdin)
+	interrupts := newInterruptHandler()
 
 	for {
 		fmt.Print("Pokedex >")
-		scanner.Scan()
+		if !scanner.Scan() {
+			// stdin is closed, e.g. Ctrl-D or the end of a piped script
+			fmt.Println()
+			commandExit(context.Background(), cliState, nil)
+		}
 		text := scanner.Text()
 		cleaned := cleanInput(text)
 
@@ -116,9 +322,13 @@ func startScanner(cliState *internal.CliState) {
 			commandArgs = cleaned[1:]
 		}
 
-		output, err := command.Callback(cliState, commandArgs)
+		ctx, release := interrupts.commandContext()
+		output, err := command.Callback(ctx, cliState, commandArgs)
+		release()
 
-		if err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("\nCancelled.")
+		} else if err != nil {
 			fmt.Println("Error:", err)
 		}
 		if output != "" {
@@ -142,7 +352,29 @@ func cleanInput(text string) []string {
 	return words
 }
 
-func commandHelp(cliState *internal.CliState, commandArgs []string) (string, error) {
+// parseFlags splits command arguments into positional arguments and --name value flags
+func parseFlags(commandArgs []string) ([]string, map[string]string) {
+	var positional []string
+	flags := make(map[string]string)
+	for i := 0; i < len(commandArgs); i++ {
+		name, isFlag := strings.CutPrefix(commandArgs[i], "--")
+		if !isFlag {
+			positional = append(positional, commandArgs[i])
+			continue
+		}
+		if before, value, found := strings.Cut(name, "="); found {
+			flags[before] = value
+		} else if i+1 < len(commandArgs) {
+			flags[name] = commandArgs[i+1]
+			i++
+		} else {
+			flags[name] = ""
+		}
+	}
+	return positional, flags
+}
+
+func commandHelp(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
 	output := "Welcome to the Pokedex!\n"
 	output += "Usage:\n\n"
 	for _, command := range cliState.AvailableCommands {
@@ -151,186 +383,326 @@ func commandHelp(cliState *internal.CliState, commandArgs []string) (string, err
 	return output, nil
 }
 
-func commandExit(cliState *internal.CliState, commandArgs []string) (string, error) {
+func commandExit(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	autosave(cliState)
+	if closer, ok := cliState.Cache.(io.Closer); ok {
+		closer.Close()
+	}
 	fmt.Println("Closing the Pokedex... Goodbye!")
 	os.Exit(0)
 	return "", nil
 }
 
-func commandMap(cliState *internal.CliState, commandArgs []string) (string, error) {
-	//increment page
-	output, err := printLocationAreasPage(cliState)
+func commandSeed(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return fmt.Sprintf("Seed: %d", cliState.Seed), nil
+	}
+	seed, err := strconv.ParseUint(commandArgs[0], 10, 64)
+	if err != nil {
+		return "Please provide the seed as a whole number, e.g. seed 42", nil
+	}
+	cliState.SetSeed(seed)
+	return fmt.Sprintf("Encounters and catches now start over from seed %d", seed), nil
+}
+
+func commandStats(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	metrics := cliState.APIService.Metrics()
+	output := "PokeAPI stats:\n"
+	output += fmt.Sprintf(" - requests: %d\n", metrics.Requests)
+	output += fmt.Sprintf(" - retries: %d\n", metrics.Retries)
+	output += fmt.Sprintf(" - cache hits: %d\n", metrics.CacheHits)
+	output += fmt.Sprintf(" - errors: %d\n", metrics.Errors)
+	output += fmt.Sprintf(" - throttled: %d (waited %s)\n", metrics.Throttled, metrics.ThrottleWait.Round(time.Millisecond))
+	return output, nil
+}
+
+func commandMap(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) > 0 {
+		if output, err := setMapRegion(ctx, cliState, commandArgs[0]); output != "" || err != nil {
+			return output, err
+		}
+	}
+
+	output, found, err := getMapPage(ctx, cliState, cliState.CurrentPage)
 	if err != nil {
 		return "", err
 	}
+	if !found {
+		return "you're on the last page", nil
+	}
+	//increment page
 	cliState.CurrentPage++
 	return output, nil
 }
 
 // if we want this to work with an undo system, we might need to do a jump back
-func commandMapBack(cliState *internal.CliState, commandArgs []string) (string, error) {
-	if cliState.CurrentPage > 1 {
-		cliState.CurrentPage = cliState.CurrentPage - 2
-	} else {
+func commandMapBack(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if cliState.CurrentPage <= 1 {
 		return "no page to go back to", nil
 	}
-	output, err := printLocationAreasPage(cliState)
+	output, _, err := getMapPage(ctx, cliState, cliState.CurrentPage-2)
 	if err != nil {
 		return "", err
 	}
+	cliState.CurrentPage = cliState.CurrentPage - 1
 	return output, nil
 }
 
-func printLocationAreasPage(cliState *internal.CliState) (string, error) {
-	// Check if we have the data in cache
-	// if cliState.Cache != nil {
-	cacheKey := fmt.Sprintf("location_areas_%d", cliState.CurrentPage)
-	cachedData, exists := cliState.Cache.Get(cacheKey)
+// setMapRegion scopes map to one region, or to every location area with "all".
+// It returns an output only when the region can't be used.
+func setMapRegion(ctx context.Context, cliState *internal.CliState, regionName string) (string, error) {
+	if regionName == "all" {
+		regionName = ""
+	} else if _, err := cliState.APIService.GetRegionContext(ctx, regionName); err != nil {
+		return explainAPIError(ctx, cliState, "region", regionName, err)
+	}
 
-	if !exists {
-		pokeService := internal.NewPokeAPIService()
-		locationAreaPage, err := pokeService.GetLocationAreas(cliState.CurrentPage)
-		if err != nil {
-			return "", fmt.Errorf("failed to load data: %w", err)
-		}
+	if regionName != cliState.MapRegion {
+		cliState.MapRegion = regionName
+		cliState.CurrentPage = 0
+	}
+	return "", nil
+}
 
-		// Convert the data to JSON for caching
-		jsonData, err := json.Marshal(locationAreaPage)
+// getMapPage renders a page of the map and reports false when the page is past the end.
+// The whole map pages through location areas, a region through its locations.
+func getMapPage(ctx context.Context, cliState *internal.CliState, pageIndex int) (string, bool, error) {
+	if cliState.MapRegion != "" {
+		page, err := getRegionLocationsPage(ctx, cliState, pageIndex)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal data for cache: %w", err)
+			return "", false, err
 		}
-
-		// Store in cache
-		cliState.Cache.Add(cacheKey, jsonData)
-		cachedData = jsonData
+		return formatRegionLocationsPage(page, cliState.MapRegion), page.Offset < page.Count, nil
 	}
 
-	// Unmarshal the cached data
-	var locationAreas []internal.LocationArea
-	if err := json.Unmarshal(cachedData, &locationAreas); err != nil {
-		return "", fmt.Errorf("failed to unmarshal cached data: %w", err)
+	page, err := getLocationAreasPage(ctx, cliState, pageIndex)
+	if err != nil {
+		return "", false, err
 	}
+	return formatLocationAreasPage(page), page.Offset < page.Count, nil
+}
+
+func getLocationAreasPage(ctx context.Context, cliState *internal.CliState, pageIndex int) (internal.Page[internal.NamedAPIResource], error) {
+	limit := cliState.PageLength
+	offset := pageIndex * limit
 
-	// Print the page
+	return cliState.APIService.ListLocationAreasContext(ctx, limit, offset)
+}
+
+func formatLocationAreasPage(page internal.Page[internal.NamedAPIResource]) string {
 	output := ""
-	// for _, locationArea := range locationAreas[pageStartIndex:pageEndIndex] { not needed with cache logic
-	for _, locationArea := range locationAreas {
+	for _, locationArea := range page.Results {
 		output += locationArea.Name + "\n"
 	}
-	return output, nil
+	output += fmt.Sprintf("page %d of %d", page.PageNumber(), page.TotalPages())
+	return output
 }
 
-func commandExplore(cliState *internal.CliState, commandArgs []string) (string, error) {
-	if len(commandArgs) == 0 {
-		return "Please provide a location area to explore", nil
+func commandExplore(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	positional, flags := parseFlags(commandArgs)
+	locationAreaName := cliState.CurrentArea
+	if len(positional) > 0 {
+		locationAreaName = positional[0]
+	}
+	if locationAreaName == "" {
+		return "Please provide a location area to explore, or goto one first", nil
 	}
 
-	locationAreaName := commandArgs[0]
+	version := flags["version"]
 	fmt.Printf("exploring %s ...\n", locationAreaName)
-	locationArea, err := getLocationArea(cliState, locationAreaName)
+	locationArea, err := cliState.APIService.GetLocationAreaContext(ctx, locationAreaName)
 	if err != nil {
-		return "", fmt.Errorf("explore failed, %w", err)
+		return explainAPIError(ctx, cliState, "location-area", locationAreaName, err)
 	}
 
-	// Use a map operation to collect Pokemon names
-	pokemonNames := make([]string, len(locationArea.PokemonEncounters))
-	for i, encounter := range locationArea.PokemonEncounters {
-		pokemonNames[i] = encounter.Pokemon.Name
+	encounters := locationArea.Encounters(version)
+	if len(encounters) == 0 {
+		if version == "" {
+			return "No wild Pokemon live here", nil
+		}
+		return fmt.Sprintf("No wild Pokemon live here in %s. Try one of: %s", version, strings.Join(locationArea.Versions(), ", ")), nil
 	}
-
-	return fmt.Sprintf("Found Pokemon:\n%s", strings.Join(pokemonNames, "\n")), nil
+	return "Found Pokemon:\n" + formatEncounters(encounters, version == ""), nil
 }
 
-func getLocationArea(cliState *internal.CliState, locationAreaName string) (internal.LocationArea, error) {
-	// Check cache first
-	cacheKey := fmt.Sprintf("location_area_%s", locationAreaName)
-	cachedData, exists := cliState.Cache.Get(cacheKey)
+// formatEncounters prints encounters as a table, with a version column when they span several versions
+func formatEncounters(encounters []internal.EncounterSummary, showVersion bool) string {
+	var sb strings.Builder
+	table := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	header := "POKEMON\tMETHOD\tLEVELS\tCHANCE\tCONDITIONS"
+	if showVersion {
+		header = "VERSION\t" + header
+	}
+	fmt.Fprintln(table, header)
 
-	if !exists {
-		pokeService := internal.NewPokeAPIService()
-		locationAreaData, err := pokeService.GetLocationArea(locationAreaName)
-		if err != nil {
-			return internal.LocationArea{}, fmt.Errorf("explore failed, %w", err)
+	for _, encounter := range encounters {
+		levels := fmt.Sprint(encounter.MinLevel)
+		if encounter.MaxLevel != encounter.MinLevel {
+			levels = fmt.Sprintf("%d-%d", encounter.MinLevel, encounter.MaxLevel)
 		}
-
-		// Convert the data to JSON for caching
-		jsonData, err := json.Marshal(locationAreaData)
-		if err != nil {
-			return internal.LocationArea{}, fmt.Errorf("failed to marshal data for cache: %w", err)
+		conditions := "-"
+		if len(encounter.Conditions) > 0 {
+			conditions = strings.Join(encounter.Conditions, ", ")
 		}
-		cliState.Cache.Add(cacheKey, jsonData)
-		cachedData = jsonData
-	}
 
-	var locationArea internal.LocationArea
-	if err := json.Unmarshal(cachedData, &locationArea); err != nil {
-		return internal.LocationArea{}, fmt.Errorf("failed to unmarshal cached data: %w", err)
+		if showVersion {
+			fmt.Fprintf(table, "%s\t", encounter.Version)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d%%\t%s\n", encounter.Pokemon, encounter.Method, levels, encounter.Chance, conditions)
 	}
-
-	return locationArea, nil
+	table.Flush()
+	return sb.String()
 }
 
-func commandCatch(cliState *internal.CliState, commandArgs []string) (string, error) {
-	pokemonName := commandArgs[0]
-	if pokemonName == "" {
-		return "What pokemon are you trying to catch?", nil
+func commandCatch(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	wild := cliState.WildEncounter
+	if wild == nil {
+		return "There is no wild Pokemon around. Try walk, surf or fish first", nil
+	}
+	pokemonName := wild.Name
+
+	positional, flags := parseFlags(commandArgs)
+	ball := "poke-ball"
+	for _, arg := range positional {
+		if internal.IsBall(ballName(arg)) {
+			ball = ballName(arg)
+		} else if arg != pokemonName {
+			return fmt.Sprintf("There is no %s here, only a wild %s", arg, pokemonName), nil
+		}
+	}
+	hp := 1.0
+	if value, ok := flags["hp"]; ok {
+		percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil || percent < 1 || percent > 100 {
+			return "Please give --hp as a percentage between 1 and 100", nil
+		}
+		hp = float64(percent) / 100
+	}
+	status := flags["status"]
+	if status != "" && !slices.Contains(internal.StatusConditions, status) {
+		return fmt.Sprintf("Unknown status %s, try one of: %s", status, strings.Join(internal.StatusConditions, ", ")), nil
+	}
+	if cliState.Inventory[ball] <= 0 {
+		return fmt.Sprintf("You have no %s left", ball), nil
 	}
 
-	fmt.Printf("Throwing a Pokeball at %s...\n", pokemonName)
-	pokemon, err := getPokemon(cliState, pokemonName)
+	pokemon, err := cliState.APIService.GetPokemonContext(ctx, pokemonName)
 	if err != nil {
-		return "", err // err already formatted in getPokemon
+		return explainAPIError(ctx, cliState, "pokemon", pokemonName, err)
 	}
-
-	chanceToCatch := rand.Intn(100) - min(95, (pokemon.BaseExperience/10))
-	if chanceToCatch > 0 {
-		cliState.Pokedex[pokemonName] = pokemon
-		return fmt.Sprintf("You caught %s!\n", pokemonName), nil
+	species, err := cliState.APIService.GetPokemonSpeciesContext(ctx, speciesName(pokemon))
+	if err != nil {
+		return "", err
 	}
-	return fmt.Sprintf("You missed %s!\n", pokemonName), nil
-}
 
-func getPokemon(cliState *internal.CliState, pokemonName string) (internal.Pokemon, error) {
-	cacheKey := fmt.Sprintf("pokemon_%s", pokemonName)
-	cachedData, exists := cliState.Cache.Get(cacheKey)
+	types := make([]string, len(pokemon.Types))
+	for i, pokemonType := range pokemon.Types {
+		types[i] = pokemonType.Type.Name
+	}
+	_, caught := cliState.Pokedex[pokemonName]
+	cliState.Inventory[ball]--
+	wild.Throws++
+	attempt := internal.CaptureAttempt{
+		CaptureRate: species.CaptureRate,
+		HP:          hp,
+		Status:      status,
+		Ball:        ball,
+		Types:       types,
+		Level:       wild.Level,
+		Turn:        wild.Throws,
+		Night:       internal.TimeOfDay(time.Now().Hour()) == "night",
+		Caught:      caught,
+	}
 
-	if !exists {
-		pokeService := internal.NewPokeAPIService()
-		pokemonData, err := pokeService.GetPokemon(pokemonName)
-		if err != nil {
-			return internal.Pokemon{}, fmt.Errorf("failed to get pokemon: %w", err)
-		}
-		// fmt.Printf("pokemonData: %+v\n", pokemonData)
-		jsonData, err := json.Marshal(pokemonData)
-		if err != nil {
-			return internal.Pokemon{}, fmt.Errorf("failed to marshal data for cache: %w", err)
-		}
-		cliState.Cache.Add(cacheKey, jsonData)
-		cachedData = jsonData
+	fmt.Printf("Throwing a %s at %s... (%.1f%% chance)\n", ball, pokemonName, attempt.Chance()*100)
+	shakes := attempt.Throw(cliState.Rand.IntN)
+	output := ""
+	for shake := 1; shake <= min(shakes, 3); shake++ {
+		output += fmt.Sprintf("%d… ", shake)
 	}
-	var pokemon internal.Pokemon
-	if err := json.Unmarshal(cachedData, &pokemon); err != nil {
-		return internal.Pokemon{}, fmt.Errorf("failed to unmarshal cached data: %w", err)
+	if shakes < 4 {
+		return output + fmt.Sprintf("Oh no! %s broke free!\n", pokemonName), nil
 	}
 
-	return pokemon, nil
+	cliState.Pokedex[pokemonName] = internal.PokedexEntry{
+		Pokemon: pokemon,
+		Level:   wild.Level,
+	}
+	cliState.WildEncounter = nil
+	return output + fmt.Sprintf("Gotcha! %s was caught!\n", pokemonName), nil
+}
+
+// ballName accepts balls with or without the -ball suffix, e.g. "great" for "great-ball"
+func ballName(name string) string {
+	if strings.HasSuffix(name, "-ball") {
+		return name
+	}
+	return name + "-ball"
 }
 
-func commandInspect(cliState *internal.CliState, commandArgs []string) (string, error) {
+func commandInspect(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
 	if len(commandArgs) == 0 {
 		return "Please provide the name of a Pokemon to inspect", nil
 	}
 	pokemonName := commandArgs[0]
-	pokemon, exists := cliState.Pokedex[pokemonName]
+	entry, exists := cliState.Pokedex[pokemonName]
 	if !exists {
 		return fmt.Sprintf("You don't have %s in your Pokedex", pokemonName), nil
 	}
+	pokemon := entry.Pokemon
+
+	output := fmt.Sprintf("Name: %s\n", pokemon.Name)
+	output += fmt.Sprintf("Level: %d\n", entry.Level)
+	output += fmt.Sprintf("Height: %d\n", pokemon.Height)
+	output += fmt.Sprintf("Weight: %d\n", pokemon.Weight)
+	output += "Stats:\n"
+	for _, stat := range pokemon.Stats {
+		output += fmt.Sprintf("  -%s: %d\n", stat.Stat.Name, stat.BaseStat)
+	}
+	output += "Types:\n"
+	for _, pokemonType := range pokemon.Types {
+		output += fmt.Sprintf("  - %s\n", pokemonType.Type.Name)
+	}
+	output += "Abilities:\n"
+	for _, pokemonAbility := range pokemon.Abilities {
+		line := "  - " + pokemonAbility.Ability.Name
+		if pokemonAbility.IsHidden {
+			line += " (hidden)"
+		}
+		ability, err := cliState.APIService.GetAbilityContext(ctx, pokemonAbility.Ability.Name)
+		if err != nil {
+			// the Pokedex data above is still worth showing
+			return output + line + "\n", fmt.Errorf("could not load ability %s: %w", pokemonAbility.Ability.Name, err)
+		}
+		if effect := ability.ShortEffect("en"); effect != "" {
+			line += ": " + effect
+		}
+		output += line + "\n"
+	}
 
-	pokemonJson, _ := json.MarshalIndent(pokemon, "", "  ")
-	return fmt.Sprintf("Pokemon %s:\n%s\n", pokemonName, pokemonJson), nil
+	species, err := cliState.APIService.GetPokemonSpeciesContext(ctx, speciesName(pokemon))
+	if err != nil {
+		// the Pokedex data above is still worth showing
+		return output, fmt.Errorf("could not load the Pokedex entry: %w", err)
+	}
+	if genus := species.Genus("en"); genus != "" {
+		output += fmt.Sprintf("The %s\n", genus)
+	}
+	if flavorText := species.FlavorText("en"); flavorText != "" {
+		output += flavorText + "\n"
+	}
+	return output, nil
+}
+
+// speciesName is the species a Pokemon belongs to, which differs from its name for alternate forms
+func speciesName(pokemon internal.Pokemon) string {
+	if pokemon.Species.Name != "" {
+		return pokemon.Species.Name
+	}
+	return pokemon.Name
 }
 
-func commandPokedex(cliState *internal.CliState, commandArgs []string) (string, error) {
+func commandPokedex(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
 	output := "Gotta catch em all!\n"
 	for pokemonName, _ := range cliState.Pokedex {
 		output += fmt.Sprintf(" - %s\n", pokemonName)
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandMoves(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	positional, flags := parseFlags(commandArgs)
+	if len(positional) == 0 {
+		return "Please provide the name of a Pokemon", nil
+	}
+	pokemonName := positional[0]
+
+	pokemon, err := findPokemon(ctx, cliState, pokemonName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "pokemon", pokemonName, err)
+	}
+
+	learnset := pokemon.Learnset(flags["method"], flags["version"])
+	if len(learnset) == 0 {
+		return fmt.Sprintf("%s learns no moves that way", pokemonName), nil
+	}
+
+	var sb strings.Builder
+	table := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "LEVEL\tMOVE\tMETHOD\tVERSION")
+	for _, entry := range learnset {
+		level := "-"
+		if entry.Method == "level-up" {
+			level = fmt.Sprint(entry.Level)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", level, entry.Move, entry.Method, entry.VersionGroup)
+	}
+	table.Flush()
+	return sb.String(), nil
+}
+
+func commandMove(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of a move", nil
+	}
+	moveName := commandArgs[0]
+
+	move, err := cliState.APIService.GetMoveContext(ctx, moveName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "move", moveName, err)
+	}
+
+	output := fmt.Sprintf("Name: %s\n", move.Name)
+	output += fmt.Sprintf("Type: %s\n", move.Type.Name)
+	output += fmt.Sprintf("Category: %s\n", move.DamageClass.Name)
+	output += fmt.Sprintf("Power: %s\n", optionalStat(move.Power))
+	output += fmt.Sprintf("Accuracy: %s\n", optionalStat(move.Accuracy))
+	output += fmt.Sprintf("PP: %s\n", optionalStat(move.PP))
+	output += fmt.Sprintf("Priority: %+d\n", move.Priority)
+	if effect := move.ShortEffect("en"); effect != "" {
+		output += effect + "\n"
+	}
+	return output, nil
+}
+
+// optionalStat prints stats PokeAPI leaves null, like the power of status moves, as a dash
+func optionalStat(stat *int) string {
+	if stat == nil {
+		return "-"
+	}
+	return fmt.Sprint(*stat)
+}
+
+// findPokemon returns a Pokemon from the Pokedex, or from the API when it hasn't been caught
+func findPokemon(ctx context.Context, cliState *internal.CliState, pokemonName string) (internal.Pokemon, error) {
+	if entry, exists := cliState.Pokedex[pokemonName]; exists {
+		return entry.Pokemon, nil
+	}
+	return cliState.APIService.GetPokemonContext(ctx, pokemonName)
+}
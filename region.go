@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandRegions(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	regionNames, err := cliState.APIService.GetResourceNamesContext(ctx, "region")
+	if err != nil {
+		return "", fmt.Errorf("failed to list regions: %w", err)
+	}
+
+	output := "Regions:\n"
+	for _, regionName := range regionNames {
+		output += fmt.Sprintf(" - %s\n", regionName)
+	}
+	return output, nil
+}
+
+func commandRegion(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of a region", nil
+	}
+	regionName := commandArgs[0]
+
+	region, err := cliState.APIService.GetRegionContext(ctx, regionName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "region", regionName, err)
+	}
+
+	output := fmt.Sprintf("Region: %s\n", region.Name)
+	if region.MainGeneration != nil {
+		generation, err := cliState.APIService.GetGenerationContext(ctx, region.MainGeneration.Name)
+		if err != nil {
+			return "", err
+		}
+		output += fmt.Sprintf("Introduced in %s with %d new Pokemon\n", generation.Name, len(generation.PokemonSpecies))
+	}
+	output += "Locations:\n"
+	for _, location := range region.Locations {
+		output += fmt.Sprintf(" - %s\n", location.Name)
+	}
+	return output, nil
+}
+
+func commandLocation(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide the name of a location", nil
+	}
+	locationName := commandArgs[0]
+
+	location, err := cliState.APIService.GetLocationContext(ctx, locationName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "location", locationName, err)
+	}
+
+	output := fmt.Sprintf("Location: %s\n", location.Name)
+	if location.Region != nil {
+		output += fmt.Sprintf("Region: %s\n", location.Region.Name)
+	}
+	if len(location.Areas) == 0 {
+		return output + "There are no areas to explore here\n", nil
+	}
+	output += "Areas:\n"
+	for _, area := range location.Areas {
+		output += fmt.Sprintf(" - %s\n", area.Name)
+	}
+	return output, nil
+}
+
+// getRegionLocationsPage pages through the locations of the map region, each with its areas
+func getRegionLocationsPage(ctx context.Context, cliState *internal.CliState, pageIndex int) (internal.Page[internal.Location], error) {
+	region, err := cliState.APIService.GetRegionContext(ctx, cliState.MapRegion)
+	if err != nil {
+		return internal.Page[internal.Location]{}, err
+	}
+
+	page := internal.Page[internal.Location]{
+		Count:  len(region.Locations),
+		Limit:  cliState.PageLength,
+		Offset: pageIndex * cliState.PageLength,
+	}
+	start := min(page.Offset, page.Count)
+	end := min(page.Offset+page.Limit, page.Count)
+	for _, regionLocation := range region.Locations[start:end] {
+		location, err := cliState.APIService.GetLocationContext(ctx, regionLocation.Name)
+		if err != nil {
+			return internal.Page[internal.Location]{}, err
+		}
+		page.Results = append(page.Results, location)
+	}
+	return page, nil
+}
+
+// formatRegionLocationsPage lists locations with their areas, the page counts locations
+func formatRegionLocationsPage(page internal.Page[internal.Location], regionName string) string {
+	output := ""
+	for _, location := range page.Results {
+		output += location.Name + "\n"
+		if len(location.Areas) == 0 {
+			output += "  (no areas to explore)\n"
+		}
+		for _, area := range location.Areas {
+			output += fmt.Sprintf("  - %s\n", area.Name)
+		}
+	}
+	output += fmt.Sprintf("locations page %d of %d in %s", page.PageNumber(), page.TotalPages(), regionName)
+	return output
+}
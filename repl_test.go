@@ -1,6 +1,16 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weirdwyrd/pokego/internal"
+	"github.com/weirdwyrd/pokego/internal/pokecache"
+)
 
 // TestCleanInput tests the input cleaning functionality
 func TestCleanInput(t *testing.T) {
@@ -32,3 +42,248 @@ func TestCleanInput(t *testing.T) {
 		}
 	}
 }
+
+// TestClosestNames tests the "did you mean" suggestions for misspelled names
+func TestClosestNames(t *testing.T) {
+	candidates := []string{"pikachu", "raichu", "pichu", "charmander", "charmeleon", "bulbasaur"}
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "pikachoo", expected: []string{"pikachu"}},
+		{input: "charm", expected: []string{"charmander", "charmeleon"}},
+		{input: "mewtwo", expected: []string{}},
+	}
+
+	for _, test := range tests {
+		actual := closestNames(test.input, candidates, 3)
+		if len(actual) != len(test.expected) {
+			t.Errorf("closestNames(%q) = %v, want %v", test.input, actual, test.expected)
+			continue
+		}
+		for i, value := range actual {
+			if value != test.expected[i] {
+				t.Errorf("closestNames(%q) = %v, want %v", test.input, actual, test.expected)
+				break
+			}
+		}
+	}
+}
+
+// TestRenderEvolutionTree tests the ASCII rendering of branching evolution chains
+func TestRenderEvolutionTree(t *testing.T) {
+	level := func(l int) []internal.EvolutionDetail {
+		return []internal.EvolutionDetail{{Trigger: internal.NamedAPIResource{Name: "level-up"}, MinLevel: &l}}
+	}
+	chain := internal.ChainLink{
+		Species: internal.NamedAPIResource{Name: "wurmple"},
+		EvolvesTo: []internal.ChainLink{
+			{
+				Species:          internal.NamedAPIResource{Name: "silcoon"},
+				EvolutionDetails: level(7),
+				EvolvesTo: []internal.ChainLink{
+					{Species: internal.NamedAPIResource{Name: "beautifly"}, EvolutionDetails: level(10)},
+				},
+			},
+			{
+				Species:          internal.NamedAPIResource{Name: "cascoon"},
+				EvolutionDetails: level(7),
+				EvolvesTo: []internal.ChainLink{
+					{Species: internal.NamedAPIResource{Name: "dustox"}, EvolutionDetails: level(10)},
+				},
+			},
+		},
+	}
+
+	expected := "wurmple\n" +
+		"|-- silcoon (level 7)\n" +
+		"|   `-- beautifly (level 10)\n" +
+		"`-- cascoon (level 7)\n" +
+		"    `-- dustox (level 10)\n"
+	if actual := renderEvolutionTree(chain); actual != expected {
+		t.Errorf("renderEvolutionTree() =\n%s\nwant\n%s", actual, expected)
+	}
+}
+
+// TestParseFlags tests splitting positional arguments from --flags
+func TestParseFlags(t *testing.T) {
+	positional, flags := parseFlags([]string{"pikachu", "--method", "level-up", "--version=red-blue", "--all"})
+
+	if len(positional) != 1 || positional[0] != "pikachu" {
+		t.Errorf("Expected [pikachu] as positional arguments, got %v", positional)
+	}
+	if flags["method"] != "level-up" || flags["version"] != "red-blue" {
+		t.Errorf("Expected method and version flags, got %v", flags)
+	}
+	if value, ok := flags["all"]; !ok || value != "" {
+		t.Errorf("Expected a valueless all flag, got %v", flags)
+	}
+}
+
+// TestSeededSession tests that the same seed replays the same encounters and catches
+func TestSeededSession(t *testing.T) {
+	play := func(seed uint64) []string {
+		cache, err := pokecache.NewCache(time.Minute)
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		defer cache.Close()
+		// everything the commands need is cached, so no request reaches PokeAPI
+		cache.Add("location-area/kanto-route-1-area", []byte(`{"name": "kanto-route-1-area",
+			"location": {"name": "kanto-route-1"},
+			"encounter_method_rates": [{"encounter_method": {"name": "walk"}, "version_details": [{"rate": 25, "version": {"name": "red"}}]}],
+			"pokemon_encounters": [
+				{"pokemon": {"name": "pidgey"}, "version_details": [{"version": {"name": "red"}, "encounter_details": [
+					{"min_level": 2, "max_level": 5, "chance": 70, "method": {"name": "walk"}, "condition_values": []}]}]},
+				{"pokemon": {"name": "rattata"}, "version_details": [{"version": {"name": "red"}, "encounter_details": [
+					{"min_level": 2, "max_level": 4, "chance": 30, "method": {"name": "walk"}, "condition_values": []}]}]}
+			]}`))
+		for _, name := range []string{"pidgey", "rattata"} {
+			cache.Add("pokemon/"+name, []byte(`{"name": "`+name+`", "species": {"name": "`+name+`"}}`))
+			cache.Add("pokemon-species/"+name, []byte(`{"name": "`+name+`", "capture_rate": 255}`))
+		}
+
+		cliState := &internal.CliState{
+			Cache:           cache,
+			APIService:      internal.NewPokeAPIService(internal.WithBaseURL("http://pokeapi.invalid/api/v2"), internal.WithCache(cache)),
+			Pokedex:         make(map[string]internal.PokedexEntry),
+			Inventory:       internal.StartingInventory(),
+			CurrentLocation: "kanto-route-1",
+			CurrentArea:     "kanto-route-1-area",
+		}
+		cliState.SetSeed(seed)
+
+		var outputs []string
+		for range 20 {
+			for _, command := range []func(context.Context, *internal.CliState, []string) (string, error){commandWalk, commandCatch} {
+				output, err := command(context.Background(), cliState, nil)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				outputs = append(outputs, output)
+			}
+		}
+		return outputs
+	}
+
+	first := play(42)
+	if !slices.ContainsFunc(first, func(output string) bool { return strings.Contains(output, "appeared") }) {
+		t.Fatalf("Expected at least one wild Pokemon in 20 steps, got %q", first)
+	}
+	if replay := play(42); !slices.Equal(first, replay) {
+		t.Errorf("Expected seed 42 to replay the same session, got\n%q\nthen\n%q", first, replay)
+	}
+	if other := play(7); slices.Equal(first, other) {
+		t.Error("Expected seed 7 to play differently from seed 42")
+	}
+}
+
+// TestExplainAPIError tests how API failures are explained to the player
+func TestExplainAPIError(t *testing.T) {
+	cache, err := pokecache.NewCache(time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+	cache.Add("list:pokemon?limit=100000&offset=0", []byte(`{"count": 3, "results": [{"name": "pikachu"}, {"name": "pichu"}, {"name": "raichu"}]}`))
+	cliState := &internal.CliState{
+		Cache:      cache,
+		APIService: internal.NewPokeAPIService(internal.WithBaseURL("http://pokeapi.invalid/api/v2"), internal.WithCache(cache)),
+	}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedOutput string // empty when an error is expected
+		expectedError  string
+	}{
+		{
+			name:           "not found with suggestions",
+			err:            &internal.HTTPStatusError{StatusCode: 404, URL: "https://pokeapi.co/api/v2/pokemon/pikachuu"},
+			expectedOutput: `There is no pokemon called "pikachuu". Did you mean pikachu`,
+		},
+		{
+			name:          "server error",
+			err:           &internal.RetryError{Attempts: 3, Err: &internal.HTTPStatusError{StatusCode: 503}},
+			expectedError: "PokeAPI is having trouble (status 503)",
+		},
+		{
+			name:          "rate limited",
+			err:           &internal.HTTPStatusError{StatusCode: 429},
+			expectedError: "PokeAPI is having trouble (status 429)",
+		},
+		{
+			name:          "client error",
+			err:           &internal.HTTPStatusError{StatusCode: 403, URL: "https://pokeapi.co/api/v2/pokemon/pikachuu"},
+			expectedError: "unexpected status code 403",
+		},
+		{
+			name:          "undecodable data",
+			err:           &internal.DecodeError{Resource: "pokemon", Err: errors.New("unexpected EOF")},
+			expectedError: "PokeAPI sent pokemon data we could not read",
+		},
+		{
+			name:          "cancelled",
+			err:           context.Canceled,
+			expectedError: context.Canceled.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := explainAPIError(context.Background(), cliState, "pokemon", "pikachuu", test.err)
+			if test.expectedError == "" {
+				if err != nil || !strings.HasPrefix(output, test.expectedOutput) {
+					t.Errorf("Expected output %q, got %q and error %v", test.expectedOutput, output, err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), test.expectedError) {
+				t.Errorf("Expected an error starting with %q, got %v", test.expectedError, err)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("Expected the original error to be wrapped, got %v", err)
+			}
+		})
+	}
+}
+
+// TestFormatRegionLocationsPage tests that region pages list and count locations, whatever their number of areas
+func TestFormatRegionLocationsPage(t *testing.T) {
+	page := internal.Page[internal.Location]{
+		Count:  3,
+		Limit:  2,
+		Offset: 2,
+		Results: []internal.Location{
+			{Name: "viridian-city", Areas: []internal.NamedAPIResource{{Name: "viridian-city-area"}, {Name: "viridian-city-gym"}}},
+		},
+	}
+	expected := "viridian-city\n  - viridian-city-area\n  - viridian-city-gym\nlocations page 2 of 2 in kanto"
+	if output := formatRegionLocationsPage(page, "kanto"); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+// TestCacheNamespaceTTLs tests that list pages keep a short TTL next to the long one of single resources
+func TestCacheNamespaceTTLs(t *testing.T) {
+	cache, err := newCache(nil, "", 0)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	memoryCache := cache.(*pokecache.Cache)
+	defer memoryCache.Close()
+
+	api := internal.NewPokeAPIService()
+	expected := map[string]time.Duration{
+		"location-area?limit=20&offset=0": listCacheTTL,
+		"pokemon?limit=100000&offset=0":   listCacheTTL,
+		"location/pallet-town":            resourceCacheTTL,
+		"pokemon/pidgey":                  resourceCacheTTL,
+	}
+	for path, ttl := range expected {
+		key := api.CacheKey(api.BaseURL() + "/" + path)
+		if actual := memoryCache.TTLFor(key); actual != ttl {
+			t.Errorf("TTLFor(%q) = %s, want %s", key, actual, ttl)
+		}
+	}
+}
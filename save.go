@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandSave(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	path, err := savePath(cliState, commandArgs)
+	if err != nil {
+		return "", err
+	}
+	if err := saveGame(cliState, path); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Saved the game to %s", path), nil
+}
+
+func commandLoad(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	path, err := savePath(cliState, commandArgs)
+	if err != nil {
+		return "", err
+	}
+	save, err := internal.ReadSave(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Sprintf("There is no save file at %s", path), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	if err := cliState.Restore(save); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Loaded %d Pokemon from %s", len(cliState.Pokedex), path), nil
+}
+
+// savePath picks the file given to save or load, switching autosave over to it
+func savePath(cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		if cliState.SavePath != "" {
+			return cliState.SavePath, nil
+		}
+		return internal.DefaultSavePath()
+	}
+	// with autosave disabled it stays disabled
+	if cliState.SavePath != "" {
+		cliState.SavePath = commandArgs[0]
+	}
+	return commandArgs[0], nil
+}
+
+func saveGame(cliState *internal.CliState, path string) error {
+	save, err := cliState.Snapshot()
+	if err != nil {
+		return err
+	}
+	if err := internal.WriteSave(path, save); err != nil {
+		return fmt.Errorf("failed to save to %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadOnStart continues the game in the save file, if there is one
+func loadOnStart(cliState *internal.CliState) {
+	if cliState.SavePath == "" {
+		return
+	}
+	save, err := internal.ReadSave(cliState.SavePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = cliState.Restore(save)
+	}
+	if err != nil {
+		// autosaving now would overwrite a file that may still be recovered
+		fmt.Printf("Could not load %s, autosave is off: %v\n", cliState.SavePath, err)
+		cliState.SavePath = ""
+		return
+	}
+	fmt.Printf("Welcome back! Loaded %d Pokemon from %s\n", len(cliState.Pokedex), cliState.SavePath)
+}
+
+// autosave saves to the save file on exit, unless autosave is off
+func autosave(cliState *internal.CliState) {
+	if cliState.SavePath == "" {
+		return
+	}
+	if err := saveGame(cliState, cliState.SavePath); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+// explainAPIError turns an API failure into something a player can act on.
+// Not-found errors become a regular output with "did you mean" suggestions,
+// server errors and rate limiting ask to try again later and anything else is returned as is.
+func explainAPIError(ctx context.Context, cliState *internal.CliState, endpoint, name string, err error) (string, error) {
+	var statusErr *internal.HTTPStatusError
+	var decodeErr *internal.DecodeError
+
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		output := fmt.Sprintf("There is no %s called %q.", strings.ReplaceAll(endpoint, "-", " "), name)
+		if suggestions := suggestNames(ctx, cliState, endpoint, name); len(suggestions) > 0 {
+			output += fmt.Sprintf(" Did you mean %s?", strings.Join(suggestions, ", "))
+		}
+		return output, nil
+	case errors.As(err, &statusErr) && (statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests):
+		return "", fmt.Errorf("PokeAPI is having trouble (status %d), try again later: %w", statusErr.StatusCode, err)
+	case errors.As(err, &decodeErr):
+		return "", fmt.Errorf("PokeAPI sent %s data we could not read: %w", decodeErr.Resource, err)
+	}
+	return "", err
+}
+
+// suggestNames returns up to three known names of the endpoint that are close to name.
+// Failing to load the names only means no suggestions.
+func suggestNames(ctx context.Context, cliState *internal.CliState, endpoint, name string) []string {
+	names, err := cliState.APIService.GetResourceNamesContext(ctx, endpoint)
+	if err != nil {
+		return nil
+	}
+	return closestNames(name, names, 3)
+}
+
+// closestNames ranks candidates by edit distance to name, with candidates containing name first
+func closestNames(name string, candidates []string, limit int) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	// allow roughly one typo every three letters
+	maxDistance := max(2, len(name)/3)
+	var matches []match
+	for _, candidate := range candidates {
+		distance := levenshtein(name, candidate)
+		if strings.Contains(candidate, name) {
+			distance = 0
+		}
+		if distance <= maxDistance {
+			matches = append(matches, match{name: candidate, distance: distance})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	names := []string{}
+	for _, m := range matches[:min(limit, len(matches))] {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// levenshtein returns the number of single-character edits needed to turn a into b
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandGoto(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		if cliState.CurrentArea == "" {
+			return "You are nowhere yet, goto any area to start your journey", nil
+		}
+		areas, err := reachableAreasOutput(ctx, cliState)
+		return fmt.Sprintf("You are in %s (%s)\n", cliState.CurrentArea, cliState.CurrentLocation) + areas, err
+	}
+	if wild := cliState.WildEncounter; wild != nil {
+		return fmt.Sprintf("A wild %s blocks your way! catch it or flee", wild.Name), nil
+	}
+
+	areaName := commandArgs[0]
+	locationArea, err := cliState.APIService.GetLocationAreaContext(ctx, areaName)
+	if err != nil {
+		return explainAPIError(ctx, cliState, "location-area", areaName, err)
+	}
+	if locationArea.Name == cliState.CurrentArea {
+		return fmt.Sprintf("You are already in %s", locationArea.Name), nil
+	}
+
+	// the first goto puts the player anywhere, after that they travel one location at a time
+	if cliState.CurrentLocation != "" {
+		reachable, err := canReach(ctx, cliState, locationArea.Location.Name)
+		if err != nil {
+			return "", err
+		}
+		if !reachable {
+			areas, err := reachableAreasOutput(ctx, cliState)
+			return fmt.Sprintf("%s is too far from %s\n", locationArea.Name, cliState.CurrentArea) + areas, err
+		}
+	}
+
+	cliState.CurrentLocation = locationArea.Location.Name
+	cliState.CurrentArea = locationArea.Name
+	return fmt.Sprintf("You arrive at %s in %s", locationArea.Name, locationArea.Location.Name), nil
+}
+
+// canReach reports whether locationName is the current location or connects to it in the region
+func canReach(ctx context.Context, cliState *internal.CliState, locationName string) (bool, error) {
+	if locationName == cliState.CurrentLocation {
+		return true, nil
+	}
+	region, err := currentRegion(ctx, cliState)
+	if err != nil || region == nil {
+		return false, err
+	}
+	return region.Adjacent(cliState.CurrentLocation, locationName), nil
+}
+
+// currentRegion returns the region of the current location, nil when it belongs to none
+func currentRegion(ctx context.Context, cliState *internal.CliState) (*internal.Region, error) {
+	location, err := cliState.APIService.GetLocationContext(ctx, cliState.CurrentLocation)
+	if err != nil {
+		return nil, err
+	}
+	if location.Region == nil {
+		return nil, nil
+	}
+	region, err := cliState.APIService.GetRegionContext(ctx, location.Region.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
+// reachableAreasOutput lists the areas of the current and neighbouring locations, except the current area
+func reachableAreasOutput(ctx context.Context, cliState *internal.CliState) (string, error) {
+	locationNames := []string{cliState.CurrentLocation}
+	region, err := currentRegion(ctx, cliState)
+	if err != nil {
+		return "", err
+	}
+	output := "From here you can go to:\n"
+	if region != nil {
+		neighbours, known := region.Neighbours(cliState.CurrentLocation)
+		if !known {
+			// without a route map every location of the region is in reach, too many to list
+			return output + fmt.Sprintf(" - any area in %s\n", region.Name), nil
+		}
+		locationNames = append(locationNames, neighbours...)
+	}
+
+	found := false
+	for _, locationName := range locationNames {
+		location, err := cliState.APIService.GetLocationContext(ctx, locationName)
+		if err != nil {
+			return "", err
+		}
+		for _, area := range location.Areas {
+			if area.Name == cliState.CurrentArea {
+				continue
+			}
+			output += fmt.Sprintf(" - %s (%s)\n", area.Name, location.Name)
+			found = true
+		}
+	}
+	if !found {
+		return "There is nowhere to go from here\n", nil
+	}
+	return output, nil
+}
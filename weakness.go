@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/weirdwyrd/pokego/internal"
+)
+
+func commandWeakness(ctx context.Context, cliState *internal.CliState, commandArgs []string) (string, error) {
+	if len(commandArgs) == 0 {
+		return "Please provide a Pokemon or up to two types", nil
+	}
+
+	defending := commandArgs[:min(2, len(commandArgs))]
+	header := strings.Join(defending, "/")
+	if name := commandArgs[0]; !internal.IsBattleType(name) {
+		types, err := pokemonTypes(ctx, cliState, name)
+		if err != nil {
+			return explainAPIError(ctx, cliState, "pokemon", name, err)
+		}
+		defending = types
+		header = fmt.Sprintf("%s (%s)", name, strings.Join(types, "/"))
+	}
+
+	chart, err := getTypeChart(ctx, cliState)
+	if err != nil {
+		return "", err
+	}
+	weaknesses, err := chart.Weaknesses(defending...)
+	if err != nil {
+		return err.Error(), nil
+	}
+
+	output := fmt.Sprintf("%s takes:\n", header)
+	for _, multiplier := range []float64{4, 2, 0.5, 0.25, 0} {
+		if attacking, ok := weaknesses[multiplier]; ok {
+			output += fmt.Sprintf("  %s from %s\n", formatMultiplier(multiplier), strings.Join(attacking, ", "))
+		}
+	}
+	return output, nil
+}
+
+// pokemonTypes returns the types of a Pokemon, preferring the Pokedex over the API
+func pokemonTypes(ctx context.Context, cliState *internal.CliState, pokemonName string) ([]string, error) {
+	pokemon, err := findPokemon(ctx, cliState, pokemonName)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]string, len(pokemon.Types))
+	for i, pokemonType := range pokemon.Types {
+		types[i] = pokemonType.Type.Name
+	}
+	return types, nil
+}
+
+func formatMultiplier(multiplier float64) string {
+	switch multiplier {
+	case 0.5:
+		return "½x"
+	case 0.25:
+		return "¼x"
+	}
+	return fmt.Sprintf("%gx", multiplier)
+}
+
+// getTypeChart builds the type chart on first use and keeps it for the rest of the session
+func getTypeChart(ctx context.Context, cliState *internal.CliState) (*internal.TypeChart, error) {
+	if cliState.TypeChart != nil {
+		return cliState.TypeChart, nil
+	}
+
+	types := make([]internal.Type, 0, len(internal.BattleTypes))
+	for _, typeName := range internal.BattleTypes {
+		pokemonType, err := cliState.APIService.GetTypeContext(ctx, typeName)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, pokemonType)
+	}
+
+	chart, err := internal.NewTypeChart(types)
+	if err != nil {
+		return nil, err
+	}
+	cliState.TypeChart = chart
+	return chart, nil
+}